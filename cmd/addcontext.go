@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+// runAddContext bootstraps one or more kubeconfig contexts from a server
+// URL template, which is handy for clusters that follow a predictable
+// naming scheme (e.g. one cluster per region or environment). It writes
+// directly to kubeconfig, so it asks for confirmation unless --force is
+// given.
+func runAddContext(args []string) {
+	fs := flag.NewFlagSet("add-context", flag.ExitOnError)
+	var names multiFlag
+	fs.Var(&names, "name", "context name to create (repeatable)")
+	serverPattern := fs.String("server-pattern", "", `cluster server URL, with "{n}" substituted for each context name`)
+	user := fs.String("user", "", "kubeconfig user to attach to each created context")
+	force := fs.Bool("force", false, "skip the confirmation prompt")
+	fs.Parse(args)
+
+	if len(names) == 0 || *serverPattern == "" || *user == "" {
+		fmt.Fprintln(os.Stderr, "kxctl add-context: --name, --server-pattern, and --user are all required")
+		os.Exit(2)
+	}
+
+	action := fmt.Sprintf("create %d context(s) in kubeconfig: %s", len(names), strings.Join(names, ", "))
+	if !*force && !confirmPrompt(action) {
+		fmt.Fprintln(os.Stderr, "aborted")
+		os.Exit(1)
+	}
+
+	if err := kube.CreateContextsFromTemplate(names, *serverPattern, *user); err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl add-context: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("created %d context(s)\n", len(names))
+}
+
+// confirmPrompt asks the user to type "yes" on stdin before a command that
+// mutates kubeconfig proceeds.
+func confirmPrompt(action string) bool {
+	fmt.Fprintf(os.Stderr, "%s - type \"yes\" to continue: ", action)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
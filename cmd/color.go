@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+const (
+	ansiBold  = "\x1b[1m"
+	ansiReset = "\x1b[0m"
+)
+
+// ansiColors cycles foreground colors for per-context output, so
+// interleaved output from many contexts is easier to tell apart at a
+// glance.
+var ansiColors = []string{
+	"\x1b[31m", // red
+	"\x1b[32m", // green
+	"\x1b[33m", // yellow
+	"\x1b[34m", // blue
+	"\x1b[35m", // magenta
+	"\x1b[36m", // cyan
+}
+
+// colorForContext derives a stable ANSI color code for name from an FNV
+// hash, so the same context name always gets the same color, both within
+// a run and across separate runs.
+func colorForContext(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return ansiColors[h.Sum32()%uint32(len(ansiColors))]
+}
+
+// resolveColor reports whether color output should be used for the given
+// --color mode: "always" and "never" force the choice, "auto" (the
+// default) falls back to colorEnabled's TTY/NO_COLOR detection.
+func resolveColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto", "":
+		return colorEnabled(), nil
+	default:
+		return false, fmt.Errorf("--color: unknown mode %q (want auto, always, or never)", mode)
+	}
+}
+
+// colorEnabled reports whether color output should be used by default:
+// stdout must be a terminal and $NO_COLOR must be unset.
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal, for
+// features like --interactive that need to fall back to a non-interactive
+// default when run in a script or pipeline.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// kubectlBinary returns the kubectl binary to shell out to: $KXCTL_KUBECTL
+// if set, for systems where it's installed under a different name or path
+// (kubectl.exe, a wrapper script, ...), falling back to "kubectl" on PATH.
+// It's only the --bin flag's default; an explicit --bin always wins.
+func kubectlBinary() string {
+	if bin := os.Getenv("KXCTL_KUBECTL"); bin != "" {
+		return bin
+	}
+	return "kubectl"
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import "testing"
+
+func TestResolveColor(t *testing.T) {
+	if got, err := resolveColor("always"); err != nil || !got {
+		t.Fatalf("resolveColor(always) = %v, %v", got, err)
+	}
+	if got, err := resolveColor("never"); err != nil || got {
+		t.Fatalf("resolveColor(never) = %v, %v", got, err)
+	}
+	if _, err := resolveColor("bogus"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestColorForContextStable(t *testing.T) {
+	a := colorForContext("prod-eu")
+	b := colorForContext("prod-eu")
+	if a != b {
+		t.Fatalf("colorForContext not stable: %q != %q", a, b)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+const bashCompletionScript = `# kxctl bash completion
+_kxctl_complete() {
+    local cur
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    COMPREPLY=($(compgen -W "$(kxctl __complete)" -- "$cur"))
+}
+complete -F _kxctl_complete -o default kxctl
+`
+
+const zshCompletionScript = `#compdef kxctl
+_kxctl() {
+    local -a contexts
+    contexts=(${(f)"$(kxctl __complete)"})
+    _describe 'context' contexts
+}
+_kxctl
+`
+
+const fishCompletionScript = `# kxctl fish completion
+complete -c kxctl -n '__fish_seen_subcommand_from exec list status -i --include -e --exclude' -f -a '(kxctl __complete)'
+`
+
+// runCompletion prints a shell completion script for the requested shell to
+// stdout, the way tools like kubectl itself and most CLIs wire up
+// "kxctl completion <shell> >> ~/.bashrc"-style installation.
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "kxctl completion: expected exactly one shell argument: bash, zsh, or fish")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletionScript)
+	case "zsh":
+		fmt.Print(zshCompletionScript)
+	case "fish":
+		fmt.Print(fishCompletionScript)
+	default:
+		fmt.Fprintf(os.Stderr, "kxctl completion: unsupported shell %q; expected bash, zsh, or fish\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// runComplete is the hidden helper the generated completion scripts call to
+// list context names for dynamic completion of -i/-e/--include/--exclude
+// values. It's best-effort: any error (no kubeconfig, kubectl missing)
+// just yields no completions instead of printing to stderr, so a shell's
+// tab-complete never shows noisy error text.
+func runComplete(args []string) {
+	client := kube.NewClient()
+	contexts, err := client.GetContexts()
+	if err != nil {
+		return
+	}
+	for _, c := range contexts {
+		fmt.Println(c)
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mpizala/kxctl/internal/config"
+)
+
+// applyConfigDefaults prepends the config file's include/exclude defaults
+// ahead of whatever was already set on the command line, unless noConfig is
+// set, in which case the command-line values are used as-is and the config
+// file is never even read.
+func applyConfigDefaults(include, exclude multiFlag, noConfig bool) (multiFlag, multiFlag, error) {
+	if noConfig {
+		return include, exclude, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(append(multiFlag{}, cfg.Include...), include...),
+		append(append(multiFlag{}, cfg.Exclude...), exclude...),
+		nil
+}
+
+// configAliases loads the config file's aliases: section, unless noConfig is
+// set, in which case no config-file aliases apply.
+func configAliases(noConfig bool) (map[string]string, error) {
+	if noConfig {
+		return nil, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Aliases, nil
+}
+
+// configTimeoutOverrides loads the config file's timeouts: section and
+// parses each duration string, unless noConfig is set, in which case no
+// per-context timeout overrides apply.
+func configTimeoutOverrides(noConfig bool) (map[string]time.Duration, error) {
+	if noConfig {
+		return nil, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Timeouts) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[string]time.Duration, len(cfg.Timeouts))
+	for pattern, s := range cfg.Timeouts {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("timeouts: %s: %w", pattern, err)
+		}
+		overrides[pattern] = d
+	}
+	return overrides, nil
+}
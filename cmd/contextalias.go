@@ -0,0 +1,51 @@
+package cmd
+
+import "github.com/mpizala/kxctl/internal/alias"
+
+// resolveAlias rewrites pattern into its real context name if it's an
+// exact alias match, following the same real-name-wins precedence as
+// resolveAliases.
+func resolveAlias(pattern string, all []string, aliases *alias.Map) string {
+	if aliases == nil || pattern == "" {
+		return pattern
+	}
+	for _, c := range all {
+		if c == pattern {
+			return pattern
+		}
+	}
+	if real, ok := aliases.Resolve(pattern); ok {
+		return real
+	}
+	return pattern
+}
+
+// resolveAliases rewrites any pattern that's an exact alias match into the
+// real context name it points to, for use with --include/--exclude/--only.
+// A pattern that's already an exact real context name is left untouched
+// even if it also happens to be a configured alias, so a collision between
+// an alias and a real name can never redirect a literal selection away
+// from the cluster it names.
+func resolveAliases(patterns []string, all []string, aliases *alias.Map) []string {
+	if aliases == nil || len(patterns) == 0 {
+		return patterns
+	}
+	realNames := make(map[string]bool, len(all))
+	for _, c := range all {
+		realNames[c] = true
+	}
+
+	resolved := make([]string, len(patterns))
+	for i, p := range patterns {
+		if realNames[p] {
+			resolved[i] = p
+			continue
+		}
+		if real, ok := aliases.Resolve(p); ok {
+			resolved[i] = real
+			continue
+		}
+		resolved[i] = p
+	}
+	return resolved
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mpizala/kxctl/internal/filter"
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+// contextInfoJSON is the --output json schema for one kxctl contexts row.
+type contextInfoJSON struct {
+	Name      string `json:"name"`
+	Server    string `json:"server"`
+	Namespace string `json:"namespace,omitempty"`
+	User      string `json:"user"`
+}
+
+// runContexts prints cluster server URL, namespace, and user for every
+// selected context, for auditing which clusters a kubeconfig points at.
+// Unlike list (names only), this always shells out to `kubectl config
+// view -o json` for the richer metadata, so it's a separate command
+// rather than a --output flag on list.
+func runContexts(args []string) {
+	fs := flag.NewFlagSet("contexts", flag.ExitOnError)
+	var include, exclude multiFlag
+	fs.Var(&include, "include", "only show contexts matching this pattern (repeatable)")
+	fs.Var(&include, "i", "short for --include")
+	fs.Var(&exclude, "exclude", "skip contexts matching this pattern (repeatable)")
+	fs.Var(&exclude, "e", "short for --exclude")
+	fuzzy := fs.Bool("fuzzy", false, "match --include/--exclude as a subsequence (like fzf) instead of a substring, e.g. -i pdeu matches prod-eu")
+	var ignoreCase bool
+	fs.BoolVar(&ignoreCase, "I", false, "match --include/--exclude case-insensitively, e.g. -I prod matches Prod-EU")
+	fs.BoolVar(&ignoreCase, "ignore-case", false, "long form of -I")
+	var includeAll bool
+	fs.BoolVar(&includeAll, "include-all", false, "require every --include pattern to match, not just one (AND semantics instead of OR); --exclude still matches any")
+	fs.BoolVar(&includeAll, "and", false, "alias for --include-all")
+	noConfig := fs.Bool("no-config", false, "ignore the config file's default --include/--exclude patterns, using only what's given on the command line")
+	output := fs.String("output", "text", "output format: text or json")
+	kubeconfig := fs.String("kubeconfig", "", "use this kubeconfig file instead of $KUBECONFIG or ~/.kube/config")
+	fs.Parse(args)
+
+	var err error
+	include, exclude, err = applyConfigDefaults(include, exclude, *noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl contexts: %v\n", err)
+		os.Exit(1)
+	}
+
+	client := kube.NewClient()
+	if *kubeconfig != "" {
+		if err := client.UseKubeconfig(*kubeconfig); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl contexts: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	infos, err := client.GetContextInfos()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl contexts: %v\n", err)
+		os.Exit(1)
+	}
+
+	names := make([]string, len(infos))
+	byName := make(map[string]kube.ContextInfo, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+		byName[info.Name] = info
+	}
+	selected := filter.Apply(names, include, exclude, *fuzzy, ignoreCase, includeAll)
+
+	if *output == "json" {
+		docs := make([]contextInfoJSON, len(selected))
+		for i, name := range selected {
+			info := byName[name]
+			docs[i] = contextInfoJSON{Name: info.Name, Server: info.Server, Namespace: info.Namespace, User: info.User}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(docs)
+		return
+	}
+
+	fmt.Printf("%-30s %-40s %-15s %s\n", "NAME", "SERVER", "NAMESPACE", "USER")
+	for _, name := range selected {
+		info := byName[name]
+		fmt.Printf("%-30s %-40s %-15s %s\n", info.Name, info.Server, info.Namespace, info.User)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mpizala/kxctl/internal/alias"
+	"github.com/mpizala/kxctl/internal/filter"
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+// runCount prints the number of contexts matching the selection flags,
+// without listing them. Unlike list, zero matches is not an error: it
+// prints "0" and exits zero, since scripts branching on cluster counts
+// shouldn't have to special-case "nothing matched" as a failure.
+func runCount(args []string) {
+	fs := flag.NewFlagSet("count", flag.ExitOnError)
+	var include, exclude multiFlag
+	fs.Var(&include, "include", "only count contexts matching this pattern (repeatable)")
+	fs.Var(&include, "i", "short for --include")
+	fs.Var(&exclude, "exclude", "skip contexts matching this pattern (repeatable)")
+	fs.Var(&exclude, "e", "short for --exclude")
+	contextAliasFile := fs.String("context-alias-file", "", "file of alias=context-name lines, merged over (and taking precedence over) any aliases: section in the config file; aliases can be used with --include/--exclude")
+	fuzzy := fs.Bool("fuzzy", false, "match --include/--exclude as a subsequence (like fzf) instead of a substring, e.g. -i pdeu matches prod-eu")
+	var ignoreCase bool
+	fs.BoolVar(&ignoreCase, "I", false, "match --include/--exclude case-insensitively, e.g. -I prod matches Prod-EU")
+	fs.BoolVar(&ignoreCase, "ignore-case", false, "long form of -I")
+	var includeAll bool
+	fs.BoolVar(&includeAll, "include-all", false, "require every --include pattern to match, not just one (AND semantics instead of OR); --exclude still matches any")
+	fs.BoolVar(&includeAll, "and", false, "alias for --include-all")
+	noConfig := fs.Bool("no-config", false, "ignore the config file's default --include/--exclude patterns, using only what's given on the command line")
+	kubeconfig := fs.String("kubeconfig", "", "use this kubeconfig file instead of $KUBECONFIG or ~/.kube/config")
+	fs.Parse(args)
+
+	var err error
+	include, exclude, err = applyConfigDefaults(include, exclude, *noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl count: %v\n", err)
+		os.Exit(1)
+	}
+
+	configAliasMap, err := configAliases(*noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl count: %v\n", err)
+		os.Exit(1)
+	}
+	aliases := alias.New(configAliasMap)
+	if *contextAliasFile != "" {
+		a, err := alias.Load(*contextAliasFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl count: %v\n", err)
+			os.Exit(1)
+		}
+		aliases.Merge(a)
+	}
+
+	client := kube.NewClient()
+	if *kubeconfig != "" {
+		if err := client.UseKubeconfig(*kubeconfig); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl count: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	all, err := client.GetContexts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl count: %v\n", err)
+		os.Exit(1)
+	}
+
+	include = resolveAliases(include, all, aliases)
+	exclude = resolveAliases(exclude, all, aliases)
+
+	targets := filter.Apply(all, include, exclude, *fuzzy, ignoreCase, includeAll)
+	fmt.Println(len(targets))
+}
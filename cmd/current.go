@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+// runCurrent prints the name of the currently active kubectl context.
+func runCurrent(args []string) {
+	client := kube.NewClient()
+	current, err := client.GetCurrentContext()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl current: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(current)
+}
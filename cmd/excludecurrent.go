@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+// excludeCurrentContext removes the currently active kubectl context from
+// targets, if present, as a safety net against accidentally running a
+// destructive command against whatever context the caller happens to be
+// debugging in locally. It's a no-op, with a warning to stderr, if the
+// current context can't be determined or isn't in targets.
+func excludeCurrentContext(targets []string, client *kube.Client) []string {
+	current, err := client.GetCurrentContext()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--exclude-current: %v\n", err)
+		return targets
+	}
+
+	out := make([]string, 0, len(targets))
+	found := false
+	for _, c := range targets {
+		if c == current {
+			found = true
+			continue
+		}
+		out = append(out, c)
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "--exclude-current: current context %q is not in the selected set, nothing excluded\n", current)
+	}
+	return out
+}
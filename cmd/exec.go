@@ -0,0 +1,1443 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/mpizala/kxctl/internal/alias"
+	"github.com/mpizala/kxctl/internal/confirm"
+	"github.com/mpizala/kxctl/internal/display"
+	"github.com/mpizala/kxctl/internal/executor"
+	"github.com/mpizala/kxctl/internal/filter"
+	"github.com/mpizala/kxctl/internal/grep"
+	"github.com/mpizala/kxctl/internal/kube"
+	"github.com/mpizala/kxctl/internal/laststate"
+	"github.com/mpizala/kxctl/internal/picker"
+	"github.com/mpizala/kxctl/internal/redact"
+	"github.com/mpizala/kxctl/internal/snapshot"
+	"github.com/mpizala/kxctl/internal/vlog"
+)
+
+type multiFlag []string
+
+func (m *multiFlag) String() string { return fmt.Sprint([]string(*m)) }
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
+
+// countFlag implements flag.Value for a repeatable flag that counts its own
+// occurrences, e.g. --verbose --verbose for level 2. IsBoolFlag lets it be
+// given bare, without a "=value", like a normal boolean flag.
+type countFlag int
+
+func (c *countFlag) String() string   { return fmt.Sprint(int(*c)) }
+func (c *countFlag) IsBoolFlag() bool { return true }
+func (c *countFlag) Set(string) error {
+	*c++
+	return nil
+}
+
+func runExec(args []string) {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	var include, exclude multiFlag
+	fs.Var(&include, "include", "only run against contexts matching this pattern (repeatable)")
+	fs.Var(&include, "i", "short for --include")
+	fs.Var(&exclude, "exclude", "skip contexts matching this pattern (repeatable)")
+	fs.Var(&exclude, "e", "short for --exclude")
+	only := fs.String("only", "", "run against exactly this context (exact match, errors if not found)")
+	selectExpr := fs.String("select", "", `select contexts with a set expression of patterns, e.g. "euprod + usprod - canary" ("+" unions, "-" subtracts, evaluated left to right); an alternative to --include/--exclude for more complex selections`)
+	extraContextsFile := fs.String("extra-contexts-file", "", "file of extra context names (one per line) to merge with kubectl's contexts; commands against them still need a matching kubeconfig entry")
+	contextsFile := fs.String("contexts-file", "", "read context names (one per line) from this file instead of running kubectl config get-contexts; for environments without a populated kubeconfig")
+	explainFilter := fs.Bool("explain-filter", false, "print why each discovered context was or wasn't selected, to stderr")
+	waitFor := fs.String("wait-for", "", "re-run the command against each context until its output matches this regexp, or --wait-for-timeout elapses")
+	waitForTimeout := fs.Duration("wait-for-timeout", 5*time.Minute, "overall deadline for --wait-for")
+	waitForInterval := fs.Duration("wait-for-interval", 5*time.Second, "how often to re-run while waiting for --wait-for")
+	excludeUnreachable := fs.Bool("exclude-unreachable", false, "probe contexts first and skip ones that don't respond")
+	probeTimeout := fs.Duration("probe-timeout", 3*time.Second, "per-context timeout for the --exclude-unreachable probe")
+	lineNumbers := fs.Bool("line-numbers", false, "prefix each line of output with a per-context line number")
+	dryRun := fs.Bool("dry-run", false, "print the kubectl invocations that would run, without executing them")
+	onError := fs.String("on-error", "", "shell command to run (via sh -c) once for each context that failed, after all contexts complete; supports {context} and {exit_code} placeholders")
+	maxAge := fs.Duration("max-age", 0, "warn if the active kubeconfig's credentials look older than this (best-effort, based on kubeconfig mtime)")
+	skipStale := fs.Bool("skip-stale", false, "with --max-age, abort instead of just warning when credentials look stale")
+	displayTrim := fs.String("display-trim", "", "regexp to strip from context names wherever they're displayed (headers, summaries); execution still uses the real name")
+	sortOutput := fs.String("sort-output", "name", "order of the final output: name, duration, exit-code, or completion (the order contexts were given in, unsorted)")
+	confirmToken := fs.String("confirm-token", "", "token from a prior --dry-run, required to actually run a write operation when set")
+	parallel := fs.Int("p", 4, "number of contexts to run against concurrently; 0 means unlimited (one goroutine per context)")
+	timeout := fs.Duration("timeout", 30*time.Second, "per-context command timeout")
+	timeoutGrace := fs.Duration("timeout-grace", 2*time.Second, "time to wait after SIGTERM before sending SIGKILL")
+	overallDeadline := fs.Duration("deadline", 0, "overall wall-clock cap across every context and retry pass combined (0 means no cap); contexts still running when it elapses are cancelled and reported as deadline-exceeded, independent of --timeout")
+	force := fs.Bool("force", false, "allow write operations (create, delete, apply, ...)")
+	echoContextEnv := fs.Bool("echo-context-env", false, "export KXCTL_CONTEXT=<name> into each command's environment")
+	allowWithoutForce := fs.String("allow-without-force", "", "comma-separated write verbs (e.g. cordon,uncordon) that skip the --force gate")
+	var envFlags multiFlag
+	fs.Var(&envFlags, "env", "context=KEY=VAL environment variable to set for a single context's kubectl invocations, e.g. for a cluster-specific AWS_PROFILE or HTTPS_PROXY (repeatable); a context without an --env entry just inherits the normal environment unchanged")
+	summaryOnly := fs.Bool("summary-only", false, "suppress per-context output and print only the pass/fail summary")
+	quiet := fs.Bool("quiet", false, "suppress the one-line \"Completed N contexts\" footer printed after each run")
+	outputDir := fs.String("output-dir", "", "write each context's output to <dir>/<context>.txt instead of printing it, e.g. for collecting diagnostics across clusters; unsafe filename characters (/, :) in a context name are replaced with _. stdout still shows the completion footer (and --summary-only's table, if set). Honors -g/--grep-invert, writing only the filtered lines")
+	expandNames := fs.Bool("expand-names", false, "expand a trailing glob in the resource name per context (e.g. `get pod myapp-*`)")
+	diffLast := fs.Bool("diff-last", false, "only print contexts whose output changed since the previous --diff-last run of this command")
+	onlyFailures := fs.Bool("only-failures", false, "suppress output from contexts that succeeded (and, with -g, had no matching lines); the completion footer and --output json/count-table still cover every context, not just the printed ones")
+	parallelRamp := fs.Duration("parallel-ramp", 0, "spread context launches evenly over this duration instead of firing -p at once")
+	output := fs.String("output", "text", "output format: text, json, null/none (discard per-context output, print only the summary), count-table (a context/match-count table built from -g), or wide-table (merge every context's tabular `get` output into one aligned table with a leading CONTEXT column)")
+	retry := fs.Int("retry", 0, "number of times to retry a context that fails, before giving up on it")
+	retryDelay := fs.Duration("retry-delay", 0, "how long to wait before each retry pass, for transient failures (auth token refreshes, flaky API servers) that need a moment to clear")
+	retryBudget := fs.Int("retry-budget", 0, "cap the total number of retries across all contexts combined (0 means no cap); protects auth providers during fleet-wide outages")
+	mergeYAML := fs.Bool("merge-yaml", false, "for `get -o yaml`, print a single multi-document YAML stream instead of one block per context")
+	watch := fs.Duration("watch", 0, "repeat the entire run every interval until interrupted (0 disables)")
+	rediscover := fs.Bool("rediscover", false, "with --watch, re-run context discovery and filtering every iteration instead of once, so kubeconfig changes are picked up without restarting")
+	concurrencyGroup := fs.String("concurrency-group", "", `regexp with a capture group that derives a concurrency throttling group from each context's name, e.g. "prod-(\\w+)-"; the -p cap applies within each group independently, so contexts in different groups always run fully in parallel with each other. Contexts that don't match share one default group`)
+	summaryFormat := fs.String("summary-format", "", `Go template for the --summary-only / --output null summary line, e.g. "{{.Completed}}/{{.Total}} ok, {{.Failed}} failed in {{.Duration}}"; defaults to the per-context table`)
+	script := fs.String("script", "", `run each line of this file (one kubectl subcommand per line, without "kubectl"; blank lines and "#" comments ignored) against every context in sequence, stopping that context's remaining steps on its first failure`)
+	argsStdin := fs.Bool("args-stdin", false, `read kubectl arg lines from stdin instead of the command line (one full command per line, e.g. "label pod foo env=prod"), running each in turn against every filtered context; for feeding in commands generated programmatically. All of stdin is read up front, before any command runs`)
+	pipeFriendly := fs.Bool("context-prefix-strip", false, `prefix every output line with "<context>\t" instead of a "Context: X" header, for piping into awk -F'\\t' or grep`)
+	noHeader := fs.Bool("no-header", false, `drop the "Context: X" header (or the --context-prefix-strip tab prefix, if both are given) entirely, printing each context's raw output back to back; combine with --output-dir or --sort when the contexts are run one at a time so the stream stays unambiguous`)
+	timings := fs.Bool("timings", false, `print how long each context's command took after its output, e.g. "  (took 2.3s)", to spot which contexts are slow; has no effect with --no-header, which wants nothing but raw output`)
+	warnDuplicateContexts := fs.Bool("warn-duplicate-contexts", false, "when $KUBECONFIG merges multiple files, warn about context names that collide and which file's entry wins")
+	grepPattern := fs.String("g", "", "regexp to count matching lines in each context's output; required by --output count-table; also used by --stream and --grep-invert to filter which lines are shown")
+	grepKeepHeader := fs.Bool("grep-keep-header", false, "with -g and --stream or --output-dir, always keep each context's first output line even if it doesn't match, so a kubectl column header survives a pattern that only matches data rows")
+	var grepInvert bool
+	fs.BoolVar(&grepInvert, "v", false, "invert -g: keep only lines that do NOT match the pattern, e.g. to hide healthy pods (like grep -v); with --output count-table, counts non-matching lines instead")
+	fs.BoolVar(&grepInvert, "grep-invert", false, "long form of -v")
+	asUser := fs.String("as", "", "impersonate this user for every kubectl invocation (global --as flag, applied before the verb)")
+	var asGroups multiFlag
+	fs.Var(&asGroups, "as-group", "impersonate this group for every kubectl invocation (repeatable, global --as-group flag)")
+	contextAliasFile := fs.String("context-alias-file", "", "file of alias=context-name lines, merged over (and taking precedence over) any aliases: section in the config file; aliases can be used with --include/--exclude/--only and are shown instead of the real name in output")
+	failOnWarning := fs.Bool("fail-on-warning", false, "treat any context that printed to stderr (even with exit code 0) as failed, for aggregation purposes")
+	warningPattern := fs.String("warning-pattern", "", "with --fail-on-warning, only stderr output matching this regexp counts as a warning")
+	fuzzy := fs.Bool("fuzzy", false, "match --include/--exclude as a subsequence (like fzf) instead of a substring, e.g. -i pdeu matches prod-eu")
+	var ignoreCase bool
+	fs.BoolVar(&ignoreCase, "I", false, "match --include/--exclude/--select case-insensitively, e.g. -I prod matches Prod-EU")
+	fs.BoolVar(&ignoreCase, "ignore-case", false, "long form of -I")
+	var includeAll bool
+	fs.BoolVar(&includeAll, "include-all", false, "require every --include pattern to match, not just one (AND semantics instead of OR); --exclude still matches any")
+	fs.BoolVar(&includeAll, "and", false, "alias for --include-all")
+	var interactive bool
+	fs.BoolVar(&interactive, "x", false, "when stdin is a terminal, show a checkbox list of the filtered contexts and let you narrow the selection before running (arrow keys, space to toggle, enter to confirm); runs against all filtered contexts unchanged when stdin isn't a terminal. Note: -i is already taken by --include and -I by --ignore-case, so this is -x / --interactive")
+	fs.BoolVar(&interactive, "interactive", false, "long form of -x")
+	heartbeat := fs.Duration("heartbeat", 0, "print \"still running: X/Y complete\" to stderr every interval during the run, to keep CI log viewers that kill jobs on silent output alive (0 disables)")
+	progressInterval := fs.Duration("progress-interval", 0, "print a \"still running against N contexts...\" progress report to stderr every interval during the run, in addition to the interactive press-Enter report (0 disables timed reporting)")
+	retryFailed := fs.Bool("retry-failed", false, "run only against the contexts that failed on the previous exec run, loaded from disk, instead of the normal --include/--exclude/--only selection")
+	adaptiveErrors := fs.Bool("parallel-adaptive-errors", false, "shrink in-flight concurrency when the recent error rate spikes (e.g. auth throttling), and grow it back once errors subside")
+	adaptiveWindow := fs.Int("parallel-adaptive-window", 10, "with --parallel-adaptive-errors, number of recent results the error rate is computed over")
+	adaptiveShrinkAt := fs.Float64("parallel-adaptive-shrink-at", 0.5, "with --parallel-adaptive-errors, error rate (0-1) over the window that triggers shrinking concurrency by one")
+	adaptiveGrowAt := fs.Float64("parallel-adaptive-grow-at", 0.1, "with --parallel-adaptive-errors, error rate (0-1) over the window at or below which concurrency grows back by one")
+	adaptiveMin := fs.Int("parallel-adaptive-min", 1, "with --parallel-adaptive-errors, the lowest concurrency backoff is allowed to shrink to")
+	var verbosity countFlag
+	fs.Var(&verbosity, "verbose", "log extra operational detail to stderr: command construction and per-context timing (repeat for concurrency gate activity too, e.g. --verbose --verbose)")
+	redactOutput := fs.Bool("redact", false, "apply best-effort redaction (IPs, emails, secret-looking tokens, and context names via --display-trim) to output before printing")
+	var redactPatterns multiFlag
+	fs.Var(&redactPatterns, "redact-pattern", "extra regexp to redact from output, replaced with <redacted> (repeatable, used with --redact)")
+	noConfig := fs.Bool("no-config", false, "ignore the config file's default --include/--exclude patterns, using only what's given on the command line")
+	color := fs.String("color", "auto", "colorize per-context \"Context: X\" headers with a color derived from the context name: auto (only when stdout is a terminal), always, or never")
+	excludeCurrent := fs.Bool("exclude-current", false, "remove the currently active kubectl context from the selected targets, as a safety net against destructive commands hitting whatever context you're debugging in locally")
+	kubeconfig := fs.String("kubeconfig", "", "use this kubeconfig file instead of $KUBECONFIG or ~/.kube/config, for every kubectl invocation kxctl makes")
+	bin := fs.String("bin", kubectlBinary(), "binary to run against each context instead of kubectl (or $KXCTL_KUBECTL, if set), e.g. helm; context selection uses --context for kubectl and --kube-context for helm, kubectl's flag for anything else")
+	failFast := fs.Bool("fail-fast", false, "cancel every other context still queued or running the moment any context's command exits non-zero, reporting them as cancelled instead of letting them run to completion")
+	streamOutput := fs.Bool("stream", false, "print each context's kubectl output live, line by line, as it arrives instead of only once the context finishes; the full output is still captured and printed normally afterwards. With -g, only lines matching the pattern are streamed")
+	eventsNDJSON := fs.Bool("events-ndjson", false, "write one JSON object per line to stderr for context-start, context-line, context-done, and run-summary events, for driving a dashboard off structured progress instead of scraping text output; normal stdout output is unaffected, so combine with --no-header/--quiet/--summary-only to suppress it")
+	var namespace string
+	fs.StringVar(&namespace, "n", "", "apply --namespace <ns> to the kubectl command for every context, unless the passthrough args already set -n/--namespace")
+	fs.StringVar(&namespace, "namespace", "", "long form of -n")
+	fs.Parse(args)
+
+	var configErr error
+	include, exclude, configErr = applyConfigDefaults(include, exclude, *noConfig)
+	if configErr != nil {
+		fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", configErr)
+		os.Exit(1)
+	}
+
+	ctx, stopSignals := executor.SignalContext()
+	defer stopSignals()
+
+	verbose := vlog.New(int(verbosity))
+
+	var summaryTmpl *template.Template
+	if *summaryFormat != "" {
+		t, err := template.New("summary-format").Parse(*summaryFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --summary-format: %v\n", err)
+			os.Exit(2)
+		}
+		summaryTmpl = t
+	}
+
+	kubectlArgs := fs.Args()
+	if *script == "" && !*argsStdin && len(kubectlArgs) == 0 {
+		fmt.Fprintln(os.Stderr, "kxctl exec: no kubectl command given")
+		os.Exit(2)
+	}
+	kubectlArgs = withNamespace(kubectlArgs, namespace)
+
+	client := kube.NewClient()
+	if *contextsFile != "" {
+		if err := client.UseContextsFile(*contextsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *kubeconfig != "" {
+		if err := client.UseKubeconfig(*kubeconfig); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	configAliasMap, err := configAliases(*noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+		os.Exit(1)
+	}
+	aliases := alias.New(configAliasMap)
+	if *contextAliasFile != "" {
+		a, err := alias.Load(*contextAliasFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+			os.Exit(1)
+		}
+		aliases.Merge(a)
+	}
+
+	if *warnDuplicateContexts {
+		dups, err := kube.DetectDuplicateContexts()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --warn-duplicate-contexts: %v\n", err)
+		}
+		for _, d := range dups {
+			fmt.Fprintf(os.Stderr, "warning: context %q is defined in multiple KUBECONFIG files; using %s, shadowing %s\n", d.Name, d.WinningFile, strings.Join(d.OtherFiles, ", "))
+		}
+	}
+
+	discover := func() ([]string, error) {
+		all, err := client.GetContexts()
+		if err != nil {
+			return nil, err
+		}
+		if *extraContextsFile != "" {
+			extra, err := kube.LoadContextsFromFile(*extraContextsFile)
+			if err != nil {
+				return nil, err
+			}
+			all = kube.MergeContexts(all, extra)
+		}
+
+		include = resolveAliases(include, all, aliases)
+		exclude = resolveAliases(exclude, all, aliases)
+		*only = resolveAlias(*only, all, aliases)
+
+		var targets []string
+		switch {
+		case *selectExpr != "":
+			targets, err = filter.Select(all, *selectExpr, *fuzzy, ignoreCase)
+			if err != nil {
+				return nil, err
+			}
+		case *only != "":
+			targets, err = filter.Only(all, *only)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			if *explainFilter {
+				explainFilterDecisions(filter.Explain(all, include, exclude, *fuzzy, ignoreCase, includeAll), includeAll)
+			}
+			targets = filter.Apply(all, include, exclude, *fuzzy, ignoreCase, includeAll)
+			for _, p := range filter.UnmatchedIncludes(all, include, *fuzzy, ignoreCase) {
+				fmt.Fprintf(os.Stderr, "warning: pattern %q matched no contexts\n", p)
+			}
+		}
+		verbose.Printf(1, "selected %d of %d discovered contexts: %s", len(targets), len(all), strings.Join(targets, ", "))
+
+		if *excludeUnreachable {
+			reachable, unreachable := executor.ProbeReachable(ctx, targets, *parallel, *probeTimeout, *kubeconfig)
+			if len(unreachable) > 0 {
+				fmt.Fprintf(os.Stderr, "excluding unreachable contexts: %s\n", strings.Join(unreachable, ", "))
+			}
+			targets = reachable
+		}
+		return targets, nil
+	}
+
+	targets, err := discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *retryFailed {
+		failed, err := laststate.LoadFailed()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --retry-failed: %v\n", err)
+			os.Exit(1)
+		}
+		if len(failed) == 0 {
+			fmt.Fprintln(os.Stderr, "kxctl exec: --retry-failed: no failed contexts recorded from a previous run")
+			os.Exit(1)
+		}
+		targets = failed
+	}
+
+	if *excludeCurrent {
+		targets = excludeCurrentContext(targets, client)
+	}
+
+	if interactive && stdinIsTerminal() {
+		picked, err := picker.Select(targets)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --interactive: %v\n", err)
+			os.Exit(1)
+		}
+		targets = picked
+	}
+
+	var allowlist []string
+	if *allowWithoutForce != "" {
+		allowlist = strings.Split(*allowWithoutForce, ",")
+	}
+
+	envOverrides, err := parseEnvOverrides(envFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl exec: --env: %v\n", err)
+		os.Exit(2)
+	}
+
+	timeoutOverrides, err := configTimeoutOverrides(*noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+		os.Exit(1)
+	}
+
+	displayName, err := display.NewResolver(*displayTrim, aliases)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl exec: --display-trim: %v\n", err)
+		os.Exit(2)
+	}
+
+	var redactor *redact.Redactor
+	if *redactOutput {
+		rd, err := redact.New(redactPatterns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+			os.Exit(2)
+		}
+		rd.WithContextNames(targets, displayName.Name)
+		redactor = rd
+	}
+
+	if *maxAge > 0 {
+		if age, err := kube.CredentialAge(); err == nil && age > *maxAge {
+			fmt.Fprintf(os.Stderr, "warning: kubeconfig credentials look stale (last refreshed %s ago, --max-age is %s)\n", age.Round(time.Second), *maxAge)
+			if *skipStale {
+				fmt.Fprintln(os.Stderr, "kxctl exec: aborting due to --skip-stale")
+				os.Exit(1)
+			}
+		}
+	}
+
+	var retryBudgetTracker *executor.RetryBudget
+	if *retryBudget > 0 {
+		retryBudgetTracker = executor.NewRetryBudget(*retryBudget)
+	}
+
+	var groupPattern *regexp.Regexp
+	if *concurrencyGroup != "" {
+		re, err := regexp.Compile(strings.Trim(*concurrencyGroup, "/"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --concurrency-group: %v\n", err)
+			os.Exit(2)
+		}
+		groupPattern = re
+	}
+
+	var adaptive *executor.AdaptiveErrorConfig
+	if *adaptiveErrors {
+		adaptive = &executor.AdaptiveErrorConfig{
+			Window:   *adaptiveWindow,
+			ShrinkAt: *adaptiveShrinkAt,
+			GrowAt:   *adaptiveGrowAt,
+			Min:      *adaptiveMin,
+			Verbose:  verbosity > 0,
+		}
+	}
+
+	var warningRe *regexp.Regexp
+	if *warningPattern != "" {
+		re, err := regexp.Compile(*warningPattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --warning-pattern: %v\n", err)
+			os.Exit(2)
+		}
+		warningRe = re
+	}
+
+	colorize, err := resolveColor(*color)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+		os.Exit(2)
+	}
+
+	var streamConfig *executor.StreamConfig
+	if *streamOutput || *eventsNDJSON {
+		streamConfig = &executor.StreamConfig{Invert: grepInvert, KeepHeader: *grepKeepHeader, Silent: !*streamOutput}
+		if *grepPattern != "" {
+			re, err := regexp.Compile(*grepPattern)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: -g: %v\n", err)
+				os.Exit(2)
+			}
+			streamConfig.Filter = re
+		}
+		if *eventsNDJSON {
+			streamConfig.Events = executor.NewEventSink(os.Stderr)
+		}
+	}
+
+	// runPass executes (or previews) one full run of kubectlArgs against
+	// targets and prints its output; it's split out so --watch can call it
+	// repeatedly. It reports whether any context failed.
+	runPass := func(targets []string) bool {
+		if *dryRun {
+			printDryRun(targets, kubectlArgs, *output == "json", *asUser, asGroups, *kubeconfig, *bin)
+			if executor.IsWriteOperation(kubectlArgs) {
+				token, err := confirm.Issue(confirm.Key(kubectlArgs, targets))
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "kxctl exec: --confirm-token: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Fprintf(os.Stderr, "confirm token for this exact command and target set: %s\n", token)
+				fmt.Fprintln(os.Stderr, "re-run with --force --confirm-token "+token+" to execute it")
+			}
+			return false
+		}
+
+		if executor.IsWriteOperation(kubectlArgs) && *confirmToken != "" {
+			ok, err := confirm.Validate(confirm.Key(kubectlArgs, targets), *confirmToken)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: --confirm-token: %v\n", err)
+				os.Exit(1)
+			}
+			if !ok {
+				fmt.Fprintln(os.Stderr, "kxctl exec: --confirm-token does not match the most recent --dry-run for this exact command and target set")
+				os.Exit(1)
+			}
+		}
+
+		runOpts := executor.RunOptions{
+			KubectlArgs:       kubectlArgs,
+			Parallel:          *parallel,
+			Timeout:           *timeout,
+			TimeoutGrace:      *timeoutGrace,
+			TimeoutOverrides:  timeoutOverrides,
+			Ramp:              *parallelRamp,
+			Deadline:          *overallDeadline,
+			Force:             *force,
+			EchoContextEnv:    *echoContextEnv,
+			EnvOverrides:      envOverrides,
+			AllowWithoutForce: allowlist,
+			AsUser:            *asUser,
+			AsGroups:          asGroups,
+			Heartbeat:         *heartbeat,
+			ProgressInterval:  *progressInterval,
+			Adaptive:          adaptive,
+			Stream:            streamConfig,
+			Verbose:           verbose,
+			Kubeconfig:        *kubeconfig,
+			Bin:               *bin,
+			FailFast:          *failFast,
+		}
+
+		var results []executor.ContextResult
+		var err error
+		if *script != "" {
+			results, err = runScript(ctx, targets, *script, runOpts, *failFast)
+		} else if *argsStdin {
+			results, err = runArgsStdin(ctx, targets, runOpts)
+		} else if *waitFor != "" {
+			results, err = runWaitFor(ctx, targets, kubectlArgs, *waitFor, *waitForTimeout, *waitForInterval, runOpts)
+		} else if *expandNames {
+			results, err = runExpandNames(ctx, targets, kubectlArgs, runOpts, *failFast)
+		} else if *retry > 0 {
+			results, err = executor.ExecuteCommandWithRetry(ctx, targets, runOpts, *retry, *retryDelay, retryBudgetTracker)
+		} else if groupPattern != nil {
+			results, err = executor.ExecuteCommandGrouped(ctx, targets, runOpts, groupPattern)
+		} else {
+			results, err = executor.ExecuteCommandRamped(ctx, targets, runOpts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+			os.Exit(1)
+		}
+		if retryBudgetTracker != nil {
+			fmt.Fprintf(os.Stderr, "retry budget consumed: %d/%d\n", retryBudgetTracker.Consumed(), *retryBudget)
+		}
+
+		if *failOnWarning {
+			applyFailOnWarning(results, warningRe)
+		}
+
+		if redactor != nil {
+			applyRedaction(results, redactor)
+		}
+
+		if err := sortResults(results, *sortOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+			os.Exit(2)
+		}
+
+		toPrint := results
+		if *diffLast {
+			toPrint, err = changedSince(kubectlArgs, results)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: --diff-last: %v\n", err)
+				toPrint = results
+			}
+		}
+
+		if *onlyFailures {
+			filtered, err := filterOnlyFailures(toPrint, *grepPattern, grepInvert)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: --only-failures: %v\n", err)
+				os.Exit(2)
+			}
+			toPrint = filtered
+		}
+
+		failed := false
+		var failedContexts []string
+		for _, r := range results {
+			if r.Err != nil {
+				failed = true
+				failedContexts = append(failedContexts, r.Context)
+			}
+		}
+		if err := laststate.SaveFailed(failedContexts); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --retry-failed: failed to record this run's failures: %v\n", err)
+		}
+
+		if streamConfig != nil && streamConfig.Events != nil {
+			streamConfig.Events.RunSummary(len(results), len(results)-len(failedContexts), len(failedContexts))
+		}
+
+		if !*quiet {
+			printCompletionFooter(results, displayName)
+		}
+
+		if *outputDir != "" {
+			if err := writeOutputDir(toPrint, displayName, *outputDir, *grepPattern, grepInvert, *grepKeepHeader); err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: --output-dir: %v\n", err)
+				os.Exit(1)
+			}
+			return failed
+		}
+
+		if *mergeYAML {
+			printMergedYAML(toPrint, displayName)
+			return failed
+		}
+
+		if *output == "wide-table" {
+			if err := printWideTable(toPrint, displayName); err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+				os.Exit(1)
+			}
+			return failed
+		}
+
+		if *output == "count-table" {
+			if *grepPattern == "" {
+				fmt.Fprintln(os.Stderr, "kxctl exec: --output count-table requires -g <pattern>")
+				os.Exit(2)
+			}
+			if err := printCountTable(toPrint, displayName, *grepPattern, grepInvert); err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+				os.Exit(1)
+			}
+			return failed
+		}
+
+		if *output == "json" {
+			if err := printJSON(toPrint); err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: %v\n", err)
+				os.Exit(1)
+			}
+			return failed
+		}
+
+		if *onError != "" {
+			runErrorHooks(*onError, results)
+		}
+
+		discard := isDiscardOutput(*output)
+		suppress := *summaryOnly || discard
+		for _, r := range toPrint {
+			if !suppress {
+				output := r.Output
+				if *lineNumbers {
+					output = numberLines(output)
+				}
+				if *noHeader {
+					fmt.Print(output)
+				} else if *pipeFriendly {
+					fmt.Print(tabPrefixLines(displayName.Name(r.Context), output))
+					if *timings {
+						fmt.Printf("%s\ttook %s\n", displayName.Name(r.Context), r.Duration.Round(time.Millisecond))
+					}
+				} else {
+					header := fmt.Sprintf("Context: %s", displayName.Name(r.Context))
+					if colorize {
+						header = colorForContext(r.Context) + header + ansiReset
+					}
+					fmt.Printf("%s\n%s\n", header, output)
+					if *timings {
+						fmt.Printf("  (took %s)\n", r.Duration.Round(time.Millisecond))
+					}
+				}
+			}
+			if r.Err != nil && !suppress {
+				fmt.Fprintf(os.Stderr, "Context: %s: %v\n", displayName.Name(r.Context), r.Err)
+			}
+		}
+		if *summaryOnly || discard {
+			printSummary(results, displayName, summaryTmpl)
+		}
+		return failed
+	}
+
+	anyFailed := false
+	for iteration := 0; ; iteration++ {
+		if iteration > 0 && *rediscover {
+			newTargets, err := discover()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kxctl exec: --rediscover: %v\n", err)
+			} else {
+				reportContextSetChange(targets, newTargets)
+				targets = newTargets
+			}
+		}
+
+		if runPass(targets) {
+			anyFailed = true
+		}
+
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "kxctl exec: interrupted, exiting")
+			os.Exit(130)
+		}
+
+		if *watch <= 0 {
+			break
+		}
+		time.Sleep(*watch)
+	}
+	if anyFailed {
+		os.Exit(1)
+	}
+}
+
+// reportContextSetChange prints, to stderr, any contexts that appeared or
+// disappeared between two --rediscover iterations of --watch.
+func reportContextSetChange(before, after []string) {
+	beforeSet := make(map[string]bool, len(before))
+	for _, c := range before {
+		beforeSet[c] = true
+	}
+	afterSet := make(map[string]bool, len(after))
+	for _, c := range after {
+		afterSet[c] = true
+	}
+
+	var added, removed []string
+	for _, c := range after {
+		if !beforeSet[c] {
+			added = append(added, c)
+		}
+	}
+	for _, c := range before {
+		if !afterSet[c] {
+			removed = append(removed, c)
+		}
+	}
+
+	if len(added) > 0 {
+		fmt.Fprintf(os.Stderr, "--rediscover: new contexts: %s\n", strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		fmt.Fprintf(os.Stderr, "--rediscover: contexts no longer present: %s\n", strings.Join(removed, ", "))
+	}
+}
+
+// applyFailOnWarning reclassifies, in place, any result that otherwise
+// succeeded but printed to stderr as failed, so it's counted as a failure
+// everywhere downstream (summary, exit code, --on-error hooks). If pattern
+// is non-nil, only stderr output matching it counts as a warning.
+func applyFailOnWarning(results []executor.ContextResult, pattern *regexp.Regexp) {
+	for i, r := range results {
+		if r.Err != nil || strings.TrimSpace(r.Stderr) == "" {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(r.Stderr) {
+			continue
+		}
+		results[i].Err = fmt.Errorf("--fail-on-warning: context printed to stderr: %s", strings.TrimSpace(r.Stderr))
+		results[i].ExitCode = 1
+	}
+}
+
+// applyRedaction scrubs Output, Stdout, and Stderr of every result in
+// place using r, so every downstream renderer (text, JSON, merged YAML)
+// sees already-redacted content.
+func applyRedaction(results []executor.ContextResult, r *redact.Redactor) {
+	for i, res := range results {
+		results[i].Output = r.Apply(res.Output)
+		results[i].Stdout = r.Apply(res.Stdout)
+		results[i].Stderr = r.Apply(res.Stderr)
+	}
+}
+
+// sortResults reorders results in place by the given key: "name"
+// (alphabetical, the default), "duration" (slowest first), "exit-code"
+// (highest first), or "completion" (a no-op, leaving results in whatever
+// order the executor already returned them in).
+func sortResults(results []executor.ContextResult, key string) error {
+	switch key {
+	case "name":
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Context < results[j].Context })
+	case "duration":
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Duration > results[j].Duration })
+	case "exit-code":
+		sort.SliceStable(results, func(i, j int) bool { return results[i].ExitCode > results[j].ExitCode })
+	case "completion":
+		// No-op: ExecuteCommand and friends already place each ContextResult
+		// at its context's input index rather than its finish order, so this
+		// mode preserves that order instead of re-sorting it by name.
+	default:
+		return fmt.Errorf("--sort-output: unknown key %q (want name, duration, exit-code, or completion)", key)
+	}
+	return nil
+}
+
+// runErrorHooks runs hookTemplate (via sh -c) once for every result that
+// failed, substituting {context} and {exit_code}. Hooks run after all
+// contexts have finished, so their output never interleaves with the run.
+func runErrorHooks(hookTemplate string, results []executor.ContextResult) {
+	for _, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		hook := strings.NewReplacer(
+			"{context}", r.Context,
+			"{exit_code}", fmt.Sprintf("%d", r.ExitCode),
+		).Replace(hookTemplate)
+
+		cmd := exec.Command("sh", "-c", hook)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "--on-error hook failed for %s: %v\n", r.Context, err)
+		}
+	}
+}
+
+// plannedCommand describes one kubectl invocation kxctl would make, for
+// --dry-run --output json.
+type plannedCommand struct {
+	Context string   `json:"context"`
+	Command []string `json:"command"`
+}
+
+// printDryRun prints, for each target, the exact kubectl invocation that
+// would run, as plain text or as a JSON array of {context, command}
+// objects when asJSON is set. It never executes anything. Targets are
+// printed sorted by context name, regardless of discovery order, so the
+// output is stable and diffable across runs.
+func printDryRun(targets, kubectlArgs []string, asJSON bool, asUser string, asGroups []string, kubeconfig string, bin string) {
+	sorted := append([]string{}, targets...)
+	sort.Strings(sorted)
+
+	if asJSON {
+		plans := make([]plannedCommand, len(sorted))
+		for i, ctxName := range sorted {
+			plans[i] = plannedCommand{Context: ctxName, Command: append([]string{bin}, executor.BuildArgs(ctxName, kubectlArgs, asUser, asGroups, kubeconfig, bin)...)}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(plans)
+		return
+	}
+
+	for _, ctxName := range sorted {
+		fmt.Printf("%s %s\n", bin, strings.Join(executor.BuildArgs(ctxName, kubectlArgs, asUser, asGroups, kubeconfig, bin), " "))
+	}
+}
+
+// printMergedYAML prints every context's output as one `---`-separated
+// multi-document YAML stream, each document preceded by a "# context: X"
+// comment so the source is still obvious once merged. It treats each
+// context's output as an opaque text block rather than parsing it, so a
+// context whose command didn't actually produce YAML (the wrong verb, or a
+// failure) gets a "# error" comment in its place instead of corrupting the
+// stream.
+func printMergedYAML(results []executor.ContextResult, displayName *display.Resolver) {
+	for _, r := range results {
+		fmt.Printf("---\n# context: %s\n", displayName.Name(r.Context))
+		if r.Err != nil {
+			fmt.Printf("# error: %v\n", r.Err)
+			continue
+		}
+		fmt.Print(strings.TrimRight(r.Output, "\n") + "\n")
+	}
+}
+
+// unsafeFilenameChars matches characters that can't safely appear in a
+// filename across common filesystems (path separators and the ':' that
+// Windows and some tar/zip tools reject), so a context name like
+// "company/prod:eu" becomes "company_prod_eu.txt".
+var unsafeFilenameChars = regexp.MustCompile(`[/:]`)
+
+// writeOutputDir writes each result's (optionally grep-filtered) output to
+// <dir>/<sanitized context name>.txt instead of printing it, for collecting
+// diagnostics across many clusters without interleaving them on stdout. A
+// context that failed still gets a file, with its error appended after the
+// output.
+func writeOutputDir(results []executor.ContextResult, displayName *display.Resolver, dir, pattern string, invert, keepHeader bool) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, r := range results {
+		output := r.Output
+		if pattern != "" {
+			filtered, err := grep.FilterLines(output, pattern, invert, keepHeader)
+			if err != nil {
+				return fmt.Errorf("context %s: %w", displayName.Name(r.Context), err)
+			}
+			output = filtered
+		}
+		name := unsafeFilenameChars.ReplaceAllString(displayName.Name(r.Context), "_")
+		path := filepath.Join(dir, name+".txt")
+		if r.Err != nil {
+			output += fmt.Sprintf("\n# error: %v\n", r.Err)
+		}
+		if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+			return fmt.Errorf("context %s: %w", displayName.Name(r.Context), err)
+		}
+	}
+	return nil
+}
+
+// runWaitFor re-runs kubectlArgs against contexts that haven't yet matched
+// pattern, at interval, until every context matches or deadline elapses. It
+// reports the last result observed for every context, so callers still not
+// ready at the deadline show their most recent (non-matching) output.
+func runWaitFor(ctx context.Context, targets, kubectlArgs []string, pattern string, deadline, interval time.Duration, opts executor.RunOptions) ([]executor.ContextResult, error) {
+	remaining := append([]string{}, targets...)
+	latest := make(map[string]executor.ContextResult, len(targets))
+	cutoff := time.Now().Add(deadline)
+	opts.KubectlArgs = kubectlArgs
+
+	for {
+		results, err := executor.ExecuteCommandWithGrace(ctx, remaining, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var stillWaiting []string
+		for _, r := range results {
+			latest[r.Context] = r
+			matched, matchErr := grep.Match(r.Output, pattern)
+			if matchErr != nil {
+				return nil, fmt.Errorf("--wait-for: %w", matchErr)
+			}
+			if !matched {
+				stillWaiting = append(stillWaiting, r.Context)
+			}
+		}
+		remaining = stillWaiting
+
+		if len(remaining) == 0 || time.Now().After(cutoff) {
+			if len(remaining) > 0 {
+				fmt.Fprintf(os.Stderr, "--wait-for: timed out waiting on: %s\n", strings.Join(remaining, ", "))
+			}
+			break
+		}
+		time.Sleep(interval)
+	}
+
+	out := make([]executor.ContextResult, len(targets))
+	for i, ctxName := range targets {
+		out[i] = latest[ctxName]
+	}
+	return out, nil
+}
+
+// runScript runs every step from scriptPath against each context in
+// sequence, stopping that context's remaining steps as soon as one fails.
+// Each context's steps are collapsed into a single ContextResult so the
+// rest of the exec pipeline (sorting, JSON, summaries, ...) can treat a
+// script run just like a single command. Because steps run one context at
+// a time, contexts also run sequentially rather than through the shared
+// concurrent executor.
+func runScript(ctx context.Context, targets []string, scriptPath string, opts executor.RunOptions, failFast bool) ([]executor.ContextResult, error) {
+	steps, err := loadScriptSteps(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("--script: %s contains no steps", scriptPath)
+	}
+
+	opts.Parallel = 1
+	opts.Deadline = 0
+	opts.FailFast = false
+
+	results := make([]executor.ContextResult, 0, len(targets))
+	cancelled := false
+	for _, ctxName := range targets {
+		if cancelled {
+			results = append(results, executor.ContextResult{Context: ctxName, Err: fmt.Errorf("cancelled by --fail-fast"), ExitCode: 1, Cancelled: true})
+			continue
+		}
+
+		var out strings.Builder
+		var total time.Duration
+		var stepErr error
+		exitCode := 0
+
+		for i, stepArgs := range steps {
+			opts.KubectlArgs = stepArgs
+			r, err := executor.ExecuteCommandWithGrace(ctx, []string{ctxName}, opts)
+			if err != nil {
+				return nil, fmt.Errorf("--script: step %d (%s): %w", i+1, strings.Join(stepArgs, " "), err)
+			}
+			step := r[0]
+			total += step.Duration
+			fmt.Fprintf(&out, "$ %s\n%s", strings.Join(stepArgs, " "), step.Output)
+			if step.Err != nil {
+				stepErr = fmt.Errorf("step %d (%s): %w", i+1, strings.Join(stepArgs, " "), step.Err)
+				exitCode = step.ExitCode
+				break
+			}
+		}
+
+		if stepErr != nil && failFast {
+			cancelled = true
+		}
+
+		results = append(results, executor.ContextResult{
+			Context:  ctxName,
+			Output:   out.String(),
+			Err:      stepErr,
+			ExitCode: exitCode,
+			Duration: total,
+		})
+	}
+	return results, nil
+}
+
+// runArgsStdin runs one full kubectl command per line of stdin against
+// every target in turn, merging each target's results across all lines into
+// a single ContextResult, the way runScript merges --script's steps. Unlike
+// --script, each line runs across every target concurrently (through the
+// normal executor) before moving on to the next line, rather than running
+// every step against one context before moving to the next context.
+//
+// stdin is read in full up front, before any line runs, so the executor's
+// own Enter-key progress reader (which also reads os.Stdin when it's a
+// terminal) never races --args-stdin for the same input.
+func runArgsStdin(ctx context.Context, targets []string, opts executor.RunOptions) ([]executor.ContextResult, error) {
+	lines, err := readArgsStdin()
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("--args-stdin: stdin contained no kubectl commands")
+	}
+
+	merged := make(map[string]*executor.ContextResult, len(targets))
+	for _, ctxName := range targets {
+		merged[ctxName] = &executor.ContextResult{Context: ctxName}
+	}
+
+	for i, line := range lines {
+		opts.KubectlArgs = strings.Fields(line)
+		results, err := executor.ExecuteCommandWithGrace(ctx, targets, opts)
+		if err != nil {
+			return nil, fmt.Errorf("--args-stdin: line %d (%s): %w", i+1, line, err)
+		}
+		for _, r := range results {
+			m := merged[r.Context]
+			m.Output += fmt.Sprintf("$ %s\n%s", line, r.Output)
+			m.Duration += r.Duration
+			if r.Err != nil {
+				m.Err = fmt.Errorf("line %d (%s): %w", i+1, line, r.Err)
+				m.ExitCode = r.ExitCode
+			}
+		}
+	}
+
+	out := make([]executor.ContextResult, len(targets))
+	for i, ctxName := range targets {
+		out[i] = *merged[ctxName]
+	}
+	return out, nil
+}
+
+// parseEnvOverrides parses repeated --env context=KEY=VAL entries into a map
+// keyed by context name, each holding that context's "KEY=VAL" entries in
+// the order given; a context named more than once just accumulates more
+// entries, with a later one overriding an earlier one of the same key the
+// way the environment normally works.
+func parseEnvOverrides(entries []string) (map[string][]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		ctxName, kv, ok := strings.Cut(entry, "=")
+		if !ok || !strings.Contains(kv, "=") {
+			return nil, fmt.Errorf("invalid entry %q, want context=KEY=VAL", entry)
+		}
+		overrides[ctxName] = append(overrides[ctxName], kv)
+	}
+	return overrides, nil
+}
+
+// readArgsStdin reads stdin and splits it into one kubectl argument line per
+// non-blank line, splitting each on whitespace the same way --script does.
+func readArgsStdin() ([]string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("--args-stdin: reading stdin: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// loadScriptSteps reads scriptPath and splits it into one kubectl argument
+// list per non-blank, non-comment line.
+func loadScriptSteps(scriptPath string) ([][]string, error) {
+	data, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("--script: %w", err)
+	}
+
+	var steps [][]string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		steps = append(steps, strings.Fields(line))
+	}
+	return steps, nil
+}
+
+// runExpandNames resolves a trailing glob in kubectlArgs's resource name
+// against each context's live resources before running the command there.
+// Because the expanded args differ per context, contexts are run one at a
+// time rather than through the shared concurrent executor.
+func runExpandNames(ctx context.Context, targets []string, kubectlArgs []string, opts executor.RunOptions, failFast bool) ([]executor.ContextResult, error) {
+	if len(kubectlArgs) < 3 {
+		return nil, fmt.Errorf("--expand-names requires a verb, resource type and name pattern, e.g. \"get pod myapp-*\"")
+	}
+	resourceType := kubectlArgs[1]
+	pattern := kubectlArgs[len(kubectlArgs)-1]
+
+	opts.Parallel = 1
+	opts.Deadline = 0
+	opts.AllowWithoutForce = nil
+	opts.FailFast = false
+
+	results := make([]executor.ContextResult, 0, len(targets))
+	cancelled := false
+	for _, ctxName := range targets {
+		if cancelled {
+			results = append(results, executor.ContextResult{Context: ctxName, Err: fmt.Errorf("cancelled by --fail-fast"), ExitCode: 1, Cancelled: true})
+			continue
+		}
+
+		names, err := kube.ExpandResourceNames(ctxName, resourceType, pattern)
+		if err != nil {
+			results = append(results, executor.ContextResult{Context: ctxName, Err: err, ExitCode: 1})
+			if failFast {
+				cancelled = true
+			}
+			continue
+		}
+		if len(names) == 0 {
+			results = append(results, executor.ContextResult{Context: ctxName, Output: fmt.Sprintf("no %s matched %q\n", resourceType, pattern)})
+			continue
+		}
+
+		expandedArgs := append(append([]string{}, kubectlArgs[:len(kubectlArgs)-1]...), names...)
+		opts.KubectlArgs = expandedArgs
+		r, err := executor.ExecuteCommandWithGrace(ctx, []string{ctxName}, opts)
+		if err != nil {
+			return nil, err
+		}
+		if failFast {
+			for _, rr := range r {
+				if rr.Err != nil {
+					cancelled = true
+				}
+			}
+		}
+		results = append(results, r...)
+	}
+	return results, nil
+}
+
+// filterOnlyFailures keeps only the results worth a human's attention: a
+// context whose command failed, or, if pattern is set, whose output also
+// has at least one line matching pattern (or not matching it, if invert is
+// set), the same sense -g/-v use everywhere else.
+func filterOnlyFailures(results []executor.ContextResult, pattern string, invert bool) ([]executor.ContextResult, error) {
+	var kept []executor.ContextResult
+	for _, r := range results {
+		if r.Err != nil {
+			kept = append(kept, r)
+			continue
+		}
+		if pattern == "" {
+			continue
+		}
+		n, err := grep.Count(r.Output, pattern, invert)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// changedSince loads the output snapshot from the previous --diff-last run
+// of this exact kubectl invocation, returns only the results whose output
+// hash differs (or is new), and persists the current results as the next
+// snapshot to compare against.
+func changedSince(kubectlArgs []string, results []executor.ContextResult) ([]executor.ContextResult, error) {
+	store, err := snapshot.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []executor.ContextResult
+	for _, r := range results {
+		key := snapshot.Key(kubectlArgs, r.Context)
+		hash := snapshot.HashOutput(r.Output)
+		if store[key] != hash {
+			changed = append(changed, r)
+		}
+		store[key] = hash
+	}
+
+	if err := snapshot.Save(store); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// explainFilterDecisions prints, for each discovered context, which include
+// pattern matched (if any) and which exclude pattern rejected it. With
+// includeAll set, the "no include pattern matched" case is rephrased since
+// --include-all requires every pattern to match, not just one.
+func explainFilterDecisions(decisions []filter.Decision, includeAll bool) {
+	for _, d := range decisions {
+		switch {
+		case d.MatchedInclude == "" && !d.Selected && includeAll:
+			fmt.Fprintf(os.Stderr, "explain-filter: %s: rejected (not every include pattern matched)\n", d.Context)
+		case d.MatchedInclude == "" && !d.Selected:
+			fmt.Fprintf(os.Stderr, "explain-filter: %s: rejected (no include pattern matched)\n", d.Context)
+		case d.MatchedExclude != "":
+			fmt.Fprintf(os.Stderr, "explain-filter: %s: rejected (matched exclude pattern %q)\n", d.Context, d.MatchedExclude)
+		case d.MatchedInclude != "":
+			fmt.Fprintf(os.Stderr, "explain-filter: %s: selected (matched include pattern %q)\n", d.Context, d.MatchedInclude)
+		default:
+			fmt.Fprintf(os.Stderr, "explain-filter: %s: selected (no include patterns given, no exclude matched)\n", d.Context)
+		}
+	}
+}
+
+// numberLines prefixes each line of output with a 1-based line number,
+// resetting per call (i.e. per context).
+func numberLines(output string) string {
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return output
+	}
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("%4d  %s", i+1, line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// tabPrefixLines prefixes every line of output with "<ctxName>\t", and no
+// header or separator, so tools like `awk -F'\t'` can split context from
+// data cleanly. It's the pipe-friendly counterpart to the default
+// "Context: X" block format.
+func tabPrefixLines(ctxName, output string) string {
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return ""
+	}
+	for i, line := range lines {
+		lines[i] = ctxName + "\t" + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// isDiscardOutput reports whether output selects the side-effect-only mode
+// that suppresses all per-context stdout/stderr.
+func isDiscardOutput(output string) bool {
+	return output == "null" || output == "none"
+}
+
+// jsonResult is the --output json schema for a single context's result.
+// When Stdout parses as JSON (the common case for `kubectl ... -o json`),
+// Data holds it as a nested object instead of an escaped string, so the
+// overall document is directly queryable; Raw holds the stdout verbatim
+// when it doesn't parse as JSON.
+type jsonResult struct {
+	Context          string          `json:"context"`
+	ExitCode         int             `json:"exitCode"`
+	Data             json.RawMessage `json:"data,omitempty"`
+	Raw              string          `json:"raw,omitempty"`
+	Stderr           string          `json:"stderr"`
+	DurationMs       int64           `json:"durationMs"`
+	TimedOut         bool            `json:"timedOut"`
+	DeadlineExceeded bool            `json:"deadlineExceeded"`
+	Cancelled        bool            `json:"cancelled"`
+	Retries          int             `json:"retries"`
+	Error            string          `json:"error,omitempty"`
+}
+
+// jsonDocument is the top-level --output json shape: a single object
+// wrapping all contexts' results, rather than a bare array, so future
+// top-level fields (e.g. run metadata) can be added without breaking
+// existing consumers that index into "results".
+type jsonDocument struct {
+	Results []jsonResult `json:"results"`
+}
+
+// printJSON writes results as a single JSON document to stdout, even when
+// some contexts failed, so downstream tooling always gets valid, uniform
+// JSON.
+func printJSON(results []executor.ContextResult) error {
+	doc := jsonDocument{Results: make([]jsonResult, len(results))}
+	for i, r := range results {
+		jr := jsonResult{
+			Context:          r.Context,
+			ExitCode:         r.ExitCode,
+			Stderr:           r.Stderr,
+			DurationMs:       r.Duration.Milliseconds(),
+			TimedOut:         r.TimedOut,
+			DeadlineExceeded: r.DeadlineExceeded,
+			Cancelled:        r.Cancelled,
+			Retries:          r.Retries,
+		}
+		if json.Valid([]byte(r.Stdout)) {
+			jr.Data = json.RawMessage(r.Stdout)
+		} else {
+			jr.Raw = r.Stdout
+		}
+		if r.Err != nil {
+			jr.Error = r.Err.Error()
+		}
+		doc.Results[i] = jr
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// printWideTable merges every context's tabular `get`-style stdout into a
+// single tabwriter-aligned table, with a leading CONTEXT column. The first
+// context with non-empty output sets the canonical header; a later context
+// whose header doesn't match gets a warning on stderr but its rows are
+// still printed under the canonical columns. A row with more columns than
+// the header has its overflow columns joined back into the last one
+// (kubectl sometimes right-pads a final free-text column like LABELS);
+// a row with fewer is padded with blanks.
+func printWideTable(results []executor.ContextResult, displayName *display.Resolver) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	var header []string
+	for _, r := range results {
+		name := displayName.Name(r.Context)
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s\tERROR: %v\n", name, r.Err)
+			continue
+		}
+
+		lines := strings.Split(strings.TrimRight(r.Stdout, "\n"), "\n")
+		if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+			continue
+		}
+
+		fields := strings.Fields(lines[0])
+		if header == nil {
+			header = fields
+			fmt.Fprintf(w, "CONTEXT\t%s\n", strings.Join(header, "\t"))
+		} else if !equalFields(fields, header) {
+			fmt.Fprintf(os.Stderr, "warning: --output wide-table: %s's header (%s) doesn't match %s (%s); printing its rows under the original columns anyway\n",
+				name, strings.Join(fields, " "), strings.Join(header, " "), strings.Join(header, " "))
+		}
+
+		for _, line := range lines[1:] {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			cols := padOrTruncateColumns(strings.Fields(line), len(header))
+			fmt.Fprintf(w, "%s\t%s\n", name, strings.Join(cols, "\t"))
+		}
+	}
+	return w.Flush()
+}
+
+// equalFields reports whether two string slices have the same elements in
+// the same order.
+func equalFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// padOrTruncateColumns makes cols exactly want long: overflow columns are
+// folded back into the last one (joined with a space) instead of being
+// dropped, and a short row is padded with empty columns.
+func padOrTruncateColumns(cols []string, want int) []string {
+	if want <= 0 {
+		return cols
+	}
+	if len(cols) > want {
+		cols = append(cols[:want-1:want-1], strings.Join(cols[want-1:], " "))
+	}
+	for len(cols) < want {
+		cols = append(cols, "")
+	}
+	return cols
+}
+
+// printCountTable prints a two-column CONTEXT/COUNT table, one row per
+// context giving how many lines of its output matched pattern (or did NOT
+// match it, if invert is set), sorted by count descending, with a trailing
+// TOTAL row.
+func printCountTable(results []executor.ContextResult, displayName *display.Resolver, pattern string, invert bool) error {
+	type row struct {
+		name  string
+		count int
+	}
+	rows := make([]row, 0, len(results))
+	total := 0
+	for _, r := range results {
+		n, err := grep.Count(r.Output, pattern, invert)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row{displayName.Name(r.Context), n})
+		total += n
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].count > rows[j].count })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "CONTEXT\tCOUNT\n")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%d\n", r.name, r.count)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\n", total)
+	return w.Flush()
+}
+
+// summaryTemplateData is the data available to a --summary-format template.
+type summaryTemplateData struct {
+	Completed int
+	Total     int
+	Failed    int
+	Duration  time.Duration // the slowest context's duration, as a stand-in for overall run time
+}
+
+// printCompletionFooter prints a one-line "Completed N contexts: X
+// succeeded, Y failed (ctx-a, ctx-b)" summary to stderr after every run,
+// naming the failed contexts explicitly so they can be re-run directly
+// (or via --retry-failed) without scrolling back through the full output.
+// It's independent of --output and --summary-only, which control the
+// per-context output above it, not this footer.
+func printCompletionFooter(results []executor.ContextResult, displayName *display.Resolver) {
+	succeeded := 0
+	var failedNames []string
+	for _, r := range results {
+		if r.Err != nil {
+			failedNames = append(failedNames, displayName.Name(r.Context))
+		} else {
+			succeeded++
+		}
+	}
+	if len(failedNames) == 0 {
+		fmt.Fprintf(os.Stderr, "Completed %d contexts: %d succeeded\n", len(results), succeeded)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Completed %d contexts: %d succeeded, %d failed (%s)\n", len(results), succeeded, len(failedNames), strings.Join(failedNames, ", "))
+}
+
+// printSummary prints one line per context with its exit code and duration,
+// or, if tmpl is non-nil, a single aggregate line rendered from tmpl
+// instead.
+func printSummary(results []executor.ContextResult, displayName *display.Resolver, tmpl *template.Template) {
+	if tmpl != nil {
+		data := summaryTemplateData{Total: len(results)}
+		for _, r := range results {
+			if r.Err != nil {
+				data.Failed++
+			} else {
+				data.Completed++
+			}
+			if r.Duration > data.Duration {
+				data.Duration = r.Duration
+			}
+		}
+		if err := tmpl.Execute(os.Stdout, data); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl exec: --summary-format: %v\n", err)
+		}
+		fmt.Println()
+		return
+	}
+
+	fmt.Printf("%-40s %-10s %-10s %-18s %s\n", "CONTEXT", "EXIT", "RETRIES", "STATUS", "DURATION")
+	for _, r := range results {
+		status := ""
+		if r.Cancelled {
+			status = "cancelled"
+		} else if r.DeadlineExceeded {
+			status = "deadline-exceeded"
+		} else if r.TimedOut {
+			status = "timed-out"
+		}
+		fmt.Printf("%-40s %-10d %-10d %-18s %s\n", displayName.Name(r.Context), r.ExitCode, r.Retries, status, r.Duration.Round(time.Millisecond))
+	}
+}
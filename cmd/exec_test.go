@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mpizala/kxctl/internal/executor"
+)
+
+func TestIsDiscardOutput(t *testing.T) {
+	cases := map[string]bool{
+		"text": false,
+		"json": false,
+		"null": true,
+		"none": true,
+	}
+	for output, want := range cases {
+		if got := isDiscardOutput(output); got != want {
+			t.Errorf("isDiscardOutput(%q) = %v, want %v", output, got, want)
+		}
+	}
+}
+
+// TestSortResultsCompletionIsNoop verifies "completion" leaves results in
+// whatever order they were already in, unlike "name" which re-sorts them.
+func TestSortResultsCompletionIsNoop(t *testing.T) {
+	results := []executor.ContextResult{{Context: "z"}, {Context: "a"}}
+	if err := sortResults(results, "completion"); err != nil {
+		t.Fatalf("sortResults: %v", err)
+	}
+	if results[0].Context != "z" || results[1].Context != "a" {
+		t.Fatalf("completion mode reordered results: %v", results)
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/mpizala/kxctl/internal/alias"
+	"github.com/mpizala/kxctl/internal/display"
+	"github.com/mpizala/kxctl/internal/filter"
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+func runList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	var include, exclude multiFlag
+	fs.Var(&include, "include", "only list contexts matching this pattern (repeatable)")
+	fs.Var(&include, "i", "short for --include")
+	fs.Var(&exclude, "exclude", "skip contexts matching this pattern (repeatable)")
+	fs.Var(&exclude, "e", "short for --exclude")
+	only := fs.String("only", "", "list exactly this context (exact match, errors if not found)")
+	displayTrim := fs.String("display-trim", "", "regexp to strip from context names when displaying them")
+	firstMatchOnly := fs.Bool("first-match-only", false, "print just the first matching context, in sorted order, instead of every match; exits non-zero if none match")
+	contextAliasFile := fs.String("context-alias-file", "", "file of alias=context-name lines, merged over (and taking precedence over) any aliases: section in the config file; aliases can be used with --include/--exclude/--only and are shown instead of the real name in output")
+	fuzzy := fs.Bool("fuzzy", false, "match --include/--exclude as a subsequence (like fzf) instead of a substring, e.g. -i pdeu matches prod-eu")
+	var ignoreCase bool
+	fs.BoolVar(&ignoreCase, "I", false, "match --include/--exclude case-insensitively, e.g. -I prod matches Prod-EU")
+	fs.BoolVar(&ignoreCase, "ignore-case", false, "long form of -I")
+	var includeAll bool
+	fs.BoolVar(&includeAll, "include-all", false, "require every --include pattern to match, not just one (AND semantics instead of OR); --exclude still matches any")
+	fs.BoolVar(&includeAll, "and", false, "alias for --include-all")
+	noConfig := fs.Bool("no-config", false, "ignore the config file's default --include/--exclude patterns, using only what's given on the command line")
+	markCurrent := fs.Bool("mark-current", false, "mark the currently active context with a trailing \"*\"")
+	kubeconfig := fs.String("kubeconfig", "", "use this kubeconfig file instead of $KUBECONFIG or ~/.kube/config")
+	fs.Parse(args)
+
+	var err error
+	include, exclude, err = applyConfigDefaults(include, exclude, *noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl list: %v\n", err)
+		os.Exit(1)
+	}
+
+	configAliasMap, err := configAliases(*noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl list: %v\n", err)
+		os.Exit(1)
+	}
+	aliases := alias.New(configAliasMap)
+	if *contextAliasFile != "" {
+		a, err := alias.Load(*contextAliasFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl list: %v\n", err)
+			os.Exit(1)
+		}
+		aliases.Merge(a)
+	}
+
+	displayName, err := display.NewResolver(*displayTrim, aliases)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl list: --display-trim: %v\n", err)
+		os.Exit(2)
+	}
+
+	client := kube.NewClient()
+	if *kubeconfig != "" {
+		if err := client.UseKubeconfig(*kubeconfig); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl list: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	all, err := client.GetContexts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl list: %v\n", err)
+		os.Exit(1)
+	}
+
+	include = resolveAliases(include, all, aliases)
+	exclude = resolveAliases(exclude, all, aliases)
+	if *only != "" {
+		*only = resolveAlias(*only, all, aliases)
+	}
+
+	targets := filter.Apply(all, include, exclude, *fuzzy, ignoreCase, includeAll)
+	for _, p := range filter.UnmatchedIncludes(all, include, *fuzzy, ignoreCase) {
+		fmt.Fprintf(os.Stderr, "warning: pattern %q matched no contexts\n", p)
+	}
+	if *only != "" {
+		targets, err = filter.Only(all, *only)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl list: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *firstMatchOnly {
+		if len(targets) == 0 {
+			fmt.Fprintln(os.Stderr, "kxctl list: --first-match-only: no context matched")
+			os.Exit(1)
+		}
+		sorted := append([]string{}, targets...)
+		sort.Strings(sorted)
+		fmt.Println(displayName.Name(sorted[0]))
+		return
+	}
+
+	var current string
+	if *markCurrent {
+		// Best-effort: no current context (or kubectl missing) just means
+		// nothing gets marked, not a hard failure of the whole listing.
+		current, _ = client.GetCurrentContext()
+	}
+
+	for _, c := range targets {
+		line := displayName.Name(c)
+		if len(include) > 0 {
+			// Highlighting operates on the real name so match spans line up;
+			// --display-trim is for plain listings.
+			line = highlightMatch(c, include, *fuzzy, ignoreCase)
+		}
+		if *markCurrent && c == current {
+			line += " *"
+		}
+		fmt.Println(line)
+	}
+}
+
+// highlightMatch bolds the portion of name that matched the first include
+// pattern, if color output is enabled. It's a no-op when there are no
+// include patterns, stdout isn't a terminal, or NO_COLOR is set.
+func highlightMatch(name string, include []string, fuzzy, ignoreCase bool) string {
+	if !colorEnabled() {
+		return name
+	}
+	for _, p := range include {
+		if start, end, ok := filter.MatchSpan(name, p, fuzzy, ignoreCase); ok {
+			return name[:start] + ansiBold + name[start:end] + ansiReset + name[end:]
+		}
+	}
+	return name
+}
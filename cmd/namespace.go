@@ -0,0 +1,30 @@
+package cmd
+
+import "strings"
+
+// hasNamespaceFlag reports whether kubectlArgs already specifies a
+// namespace via -n or --namespace, in any of the forms kubectl accepts
+// (separate value or "=value").
+func hasNamespaceFlag(kubectlArgs []string) bool {
+	for _, a := range kubectlArgs {
+		switch {
+		case a == "-n", a == "--namespace":
+			return true
+		case strings.HasPrefix(a, "-n="), strings.HasPrefix(a, "--namespace="):
+			return true
+		}
+	}
+	return false
+}
+
+// withNamespace appends --namespace ns to a copy of kubectlArgs, unless ns
+// is empty or kubectlArgs already sets a namespace, so a global -n/--namespace
+// flag never fights with one the caller already passed through.
+func withNamespace(kubectlArgs []string, ns string) []string {
+	if ns == "" || hasNamespaceFlag(kubectlArgs) {
+		return kubectlArgs
+	}
+	out := make([]string, 0, len(kubectlArgs)+2)
+	out = append(out, kubectlArgs...)
+	return append(out, "--namespace", ns)
+}
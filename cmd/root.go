@@ -0,0 +1,68 @@
+// Package cmd implements the kxctl subcommands.
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `kxctl - run kubectl across multiple Kubernetes contexts
+
+Usage:
+  kxctl <command> [flags]
+
+Commands:
+  exec         Run a kubectl command against selected contexts
+  list         List the contexts that match the selection flags
+  count        Print the number of contexts that match the selection flags
+  contexts     Print each context's cluster server URL, namespace, and user
+  status       Print a quick readiness summary for selected contexts
+  top          Run kubectl top across contexts and print an aggregate total
+  current      Print the currently active kubectl context
+  add-context  Create kubeconfig contexts from a server URL template
+  version      Print kxctl's version and build info
+  completion   Print a shell completion script (bash, zsh, fish)
+
+Use "kxctl <command> -h" for flags specific to a command.
+`
+
+// Execute parses os.Args and dispatches to the requested subcommand.
+func Execute() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "exec":
+		runExec(os.Args[2:])
+	case "list":
+		runList(os.Args[2:])
+	case "count":
+		runCount(os.Args[2:])
+	case "contexts":
+		runContexts(os.Args[2:])
+	case "status":
+		runStatus(os.Args[2:])
+	case "top":
+		runTop(os.Args[2:])
+	case "current":
+		runCurrent(os.Args[2:])
+	case "add-context":
+		runAddContext(os.Args[2:])
+	case "version":
+		runVersion(os.Args[2:])
+	case "completion":
+		runCompletion(os.Args[2:])
+	case "__complete":
+		// Hidden: not listed in usage, only invoked by the generated
+		// completion scripts to list context names for dynamic completion.
+		runComplete(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "kxctl: unknown command %q\n\n", os.Args[1])
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+}
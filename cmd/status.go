@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mpizala/kxctl/internal/executor"
+	"github.com/mpizala/kxctl/internal/filter"
+	"github.com/mpizala/kxctl/internal/kube"
+)
+
+// StatusRecord is the structured result of checking one context's health.
+type StatusRecord struct {
+	Context         string
+	Reachable       bool
+	ProblematicPods []string // non-Running, non-Succeeded pods, as "namespace/name"
+	Err             error
+}
+
+var problematicPodArgs = []string{
+	"get", "pods",
+	"--field-selector", "status.phase!=Running,status.phase!=Succeeded",
+	"-o", "name",
+}
+
+// RunStatus checks each context for pods that aren't Running or Succeeded,
+// which also serves as a reachability probe: a context that can't even
+// list pods is reported unreachable. It returns structured records so
+// callers can render them however they like (text, JSON, ...).
+//
+// By default every context is checked across all namespaces. If namespaces
+// is non-nil, each context is instead checked only in its own entry from
+// namespaces (falling back to "default" if that context has none set),
+// which loses the usual cross-context concurrency since each context needs
+// its own argument list.
+//
+// kubeconfig, if set, is passed to every kubectl invocation instead of
+// relying on $KUBECONFIG or kubectl's default.
+func RunStatus(ctx context.Context, targets []string, parallel int, timeout time.Duration, namespaces map[string]string, kubeconfig string) []StatusRecord {
+	if namespaces == nil {
+		results, _ := executor.ExecuteCommandWithGrace(ctx, targets, executor.RunOptions{
+			KubectlArgs:  withPodArgs("--all-namespaces"),
+			Parallel:     parallel,
+			Timeout:      timeout,
+			TimeoutGrace: 2 * time.Second,
+			Kubeconfig:   kubeconfig,
+			Bin:          "kubectl",
+		})
+		return toStatusRecords(results)
+	}
+
+	records := make([]StatusRecord, 0, len(targets))
+	for _, c := range targets {
+		ns := namespaces[c]
+		if ns == "" {
+			ns = "default"
+		}
+		results, _ := executor.ExecuteCommandWithGrace(ctx, []string{c}, executor.RunOptions{
+			KubectlArgs:  withPodArgs("--namespace", ns),
+			Parallel:     1,
+			Timeout:      timeout,
+			TimeoutGrace: 2 * time.Second,
+			Kubeconfig:   kubeconfig,
+			Bin:          "kubectl",
+		})
+		records = append(records, toStatusRecords(results)...)
+	}
+	return records
+}
+
+// withPodArgs appends extra args (the namespace selector) to a copy of
+// problematicPodArgs, since the base slice is shared across calls.
+func withPodArgs(extra ...string) []string {
+	args := make([]string, 0, len(problematicPodArgs)+len(extra))
+	args = append(args, problematicPodArgs...)
+	return append(args, extra...)
+}
+
+func toStatusRecords(results []executor.ContextResult) []StatusRecord {
+	records := make([]StatusRecord, len(results))
+	for i, r := range results {
+		rec := StatusRecord{Context: r.Context, Err: r.Err, Reachable: r.Err == nil}
+		if rec.Reachable {
+			for _, line := range strings.Split(strings.TrimSpace(r.Output), "\n") {
+				if line != "" {
+					rec.ProblematicPods = append(rec.ProblematicPods, line)
+				}
+			}
+		}
+		records[i] = rec
+	}
+	return records
+}
+
+func runStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	var include, exclude multiFlag
+	fs.Var(&include, "include", "only check contexts matching this pattern (repeatable)")
+	fs.Var(&include, "i", "short for --include")
+	fs.Var(&exclude, "exclude", "skip contexts matching this pattern (repeatable)")
+	fs.Var(&exclude, "e", "short for --exclude")
+	parallel := fs.Int("p", 4, "number of contexts to check concurrently; 0 means unlimited (one goroutine per context)")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-context command timeout")
+	excludeUnreachable := fs.Bool("exclude-unreachable", false, "probe contexts first and skip ones that don't respond")
+	probeTimeout := fs.Duration("probe-timeout", 3*time.Second, "per-context timeout for the --exclude-unreachable probe")
+	namespaceFromContext := fs.Bool("namespace-from-context", false, "check each context's own configured default namespace instead of all namespaces (mutually exclusive with the default -A behavior)")
+	fuzzy := fs.Bool("fuzzy", false, "match --include/--exclude as a subsequence (like fzf) instead of a substring, e.g. -i pdeu matches prod-eu")
+	var ignoreCase bool
+	fs.BoolVar(&ignoreCase, "I", false, "match --include/--exclude case-insensitively, e.g. -I prod matches Prod-EU")
+	fs.BoolVar(&ignoreCase, "ignore-case", false, "long form of -I")
+	var includeAll bool
+	fs.BoolVar(&includeAll, "include-all", false, "require every --include pattern to match, not just one (AND semantics instead of OR); --exclude still matches any")
+	fs.BoolVar(&includeAll, "and", false, "alias for --include-all")
+	noConfig := fs.Bool("no-config", false, "ignore the config file's default --include/--exclude patterns, using only what's given on the command line")
+	excludeCurrent := fs.Bool("exclude-current", false, "remove the currently active kubectl context from the selected targets")
+	kubeconfig := fs.String("kubeconfig", "", "use this kubeconfig file instead of $KUBECONFIG or ~/.kube/config, for every kubectl invocation kxctl makes")
+	var namespace string
+	fs.StringVar(&namespace, "n", "", "check only this namespace in every context, instead of the default -A across all namespaces (mutually exclusive with --namespace-from-context)")
+	fs.StringVar(&namespace, "namespace", "", "long form of -n")
+	fs.Parse(args)
+
+	var err error
+	include, exclude, err = applyConfigDefaults(include, exclude, *noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl status: %v\n", err)
+		os.Exit(1)
+	}
+
+	client := kube.NewClient()
+	if *kubeconfig != "" {
+		if err := client.UseKubeconfig(*kubeconfig); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl status: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	all, err := client.GetContexts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl status: %v\n", err)
+		os.Exit(1)
+	}
+	targets := filter.Apply(all, include, exclude, *fuzzy, ignoreCase, includeAll)
+
+	if *excludeCurrent {
+		targets = excludeCurrentContext(targets, client)
+	}
+
+	ctx, stop := executor.SignalContext()
+	defer stop()
+
+	if *excludeUnreachable {
+		reachable, unreachable := executor.ProbeReachable(ctx, targets, *parallel, *probeTimeout, *kubeconfig)
+		if len(unreachable) > 0 {
+			fmt.Fprintf(os.Stderr, "excluding unreachable contexts: %s\n", strings.Join(unreachable, ", "))
+		}
+		targets = reachable
+	}
+
+	var namespaces map[string]string
+	switch {
+	case *namespaceFromContext:
+		infos, err := client.GetContextInfos()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl status: %v\n", err)
+			os.Exit(1)
+		}
+		namespaces = make(map[string]string, len(infos))
+		for _, info := range infos {
+			namespaces[info.Name] = info.Namespace
+		}
+	case namespace != "":
+		namespaces = make(map[string]string, len(targets))
+		for _, ctxName := range targets {
+			namespaces[ctxName] = namespace
+		}
+	}
+
+	printStatus(RunStatus(ctx, targets, *parallel, *timeout, namespaces, *kubeconfig))
+}
+
+// printStatus renders status records the way runStatus has always printed
+// them: "ok" when nothing's wrong, a problem count otherwise.
+func printStatus(records []StatusRecord) {
+	for _, rec := range records {
+		switch {
+		case !rec.Reachable:
+			fmt.Printf("%-40s %s\n", rec.Context, "unreachable")
+		case len(rec.ProblematicPods) == 0:
+			fmt.Printf("%-40s %s\n", rec.Context, "ok")
+		default:
+			fmt.Printf("%-40s %d problematic pod(s)\n", rec.Context, len(rec.ProblematicPods))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mpizala/kxctl/internal/executor"
+	"github.com/mpizala/kxctl/internal/filter"
+	"github.com/mpizala/kxctl/internal/kube"
+	"github.com/mpizala/kxctl/internal/topstat"
+)
+
+// runTop wraps `kubectl top` (nodes or pods) for the selected contexts,
+// printing each context's own table as usual plus a grand total across
+// all of them. A context whose metrics-server isn't installed (kubectl
+// errors) is reported but excluded from the total rather than failing
+// the whole command.
+func runTop(args []string) {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	var include, exclude multiFlag
+	fs.Var(&include, "include", "only run against contexts matching this pattern (repeatable)")
+	fs.Var(&include, "i", "short for --include")
+	fs.Var(&exclude, "exclude", "skip contexts matching this pattern (repeatable)")
+	fs.Var(&exclude, "e", "short for --exclude")
+	parallel := fs.Int("p", 4, "number of contexts to query concurrently; 0 means unlimited (one goroutine per context)")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-context command timeout")
+	fuzzy := fs.Bool("fuzzy", false, "match --include/--exclude as a subsequence (like fzf) instead of a substring, e.g. -i pdeu matches prod-eu")
+	var ignoreCase bool
+	fs.BoolVar(&ignoreCase, "I", false, "match --include/--exclude case-insensitively, e.g. -I prod matches Prod-EU")
+	fs.BoolVar(&ignoreCase, "ignore-case", false, "long form of -I")
+	var includeAll bool
+	fs.BoolVar(&includeAll, "include-all", false, "require every --include pattern to match, not just one (AND semantics instead of OR); --exclude still matches any")
+	fs.BoolVar(&includeAll, "and", false, "alias for --include-all")
+	noConfig := fs.Bool("no-config", false, "ignore the config file's default --include/--exclude patterns, using only what's given on the command line")
+	kubeconfig := fs.String("kubeconfig", "", "use this kubeconfig file instead of $KUBECONFIG or ~/.kube/config, for every kubectl invocation kxctl makes")
+	fs.Parse(args)
+
+	var err error
+	include, exclude, err = applyConfigDefaults(include, exclude, *noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl top: %v\n", err)
+		os.Exit(1)
+	}
+
+	topArgs := fs.Args()
+	if len(topArgs) == 0 {
+		fmt.Fprintln(os.Stderr, "kxctl top: no top target given, e.g. \"kxctl top -- nodes\" or \"kxctl top -- pods\"")
+		os.Exit(2)
+	}
+
+	client := kube.NewClient()
+	if *kubeconfig != "" {
+		if err := client.UseKubeconfig(*kubeconfig); err != nil {
+			fmt.Fprintf(os.Stderr, "kxctl top: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	all, err := client.GetContexts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl top: %v\n", err)
+		os.Exit(1)
+	}
+	targets := filter.Apply(all, include, exclude, *fuzzy, ignoreCase, includeAll)
+
+	timeoutOverrides, err := configTimeoutOverrides(*noConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kxctl top: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := executor.SignalContext()
+	defer stop()
+
+	results, _ := executor.ExecuteCommand(targets, executor.ExecOptions{
+		KubectlArgs:      append([]string{"top"}, topArgs...),
+		Timeout:          *timeout,
+		TimeoutOverrides: timeoutOverrides,
+		MaxParallel:      *parallel,
+		Ctx:              ctx,
+	})
+
+	printTop(results)
+}
+
+// printTop prints each context's raw `kubectl top` output followed by a
+// grand total of CPU and memory usage across every context whose output
+// parsed cleanly. A context that errored (most commonly metrics-server
+// not being installed) or produced output printTop can't parse is
+// reported separately and left out of the total.
+func printTop(results []executor.ContextResult) {
+	var total topstat.Totals
+	var excluded []string
+	for _, r := range results {
+		fmt.Printf("Context: %s\n%s\n", r.Context, strings.TrimRight(r.Output, "\n"))
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Context: %s: %v\n", r.Context, r.Err)
+			excluded = append(excluded, r.Context)
+			continue
+		}
+		totals, err := topstat.Parse(r.Output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Context: %s: %v\n", r.Context, err)
+			excluded = append(excluded, r.Context)
+			continue
+		}
+		total = total.Add(totals)
+	}
+
+	fmt.Printf("\nTOTAL across %d context(s): %s CPU, %s memory\n", len(results)-len(excluded), topstat.FormatCPU(total.CPUCores), topstat.FormatMemory(total.MemoryBytes))
+	if len(excluded) > 0 {
+		fmt.Printf("excluded from total (metrics unavailable): %s\n", strings.Join(excluded, ", "))
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// version, commit, and date are set via -ldflags at build time; they default
+// to placeholders for `go run`/`go build` without them.
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// versionInfo is a one-shot environment report for bug triage: kxctl's own
+// build info plus the toolchain and kubectl client it's running against.
+type versionInfo struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	Date            string `json:"date"`
+	GoVersion       string `json:"go_version"`
+	KubectlVersion  string `json:"kubectl_version,omitempty"`
+	KubectlDetected bool   `json:"kubectl_detected"`
+}
+
+func runVersion(args []string) {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	output := fs.String("output", "text", "output format: text or json (includes Go version and detected kubectl client version)")
+	fs.Parse(args)
+
+	if *output != "json" {
+		fmt.Printf("kxctl %s (commit %s, built %s)\n", version, commit, date)
+		return
+	}
+
+	info := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+	}
+	if kv, err := kubectlClientVersion(); err == nil {
+		info.KubectlVersion = kv
+		info.KubectlDetected = true
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(info)
+}
+
+// kubectlClientVersion shells out to `kubectl version --client -o json` and
+// pulls out the client's gitVersion. It returns an error if kubectl isn't
+// on PATH or its output can't be parsed, so callers can omit the field
+// instead of failing the whole report.
+func kubectlClientVersion() (string, error) {
+	out, err := exec.Command(kubectlBinary(), "version", "--client", "-o", "json").Output()
+	if err != nil {
+		return "", err
+	}
+
+	var parsed struct {
+		ClientVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"clientVersion"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return "", err
+	}
+	return parsed.ClientVersion.GitVersion, nil
+}
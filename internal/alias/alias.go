@@ -0,0 +1,97 @@
+// Package alias maps short human-friendly names to real Kubernetes context
+// names (e.g. ARNs), so selection and display can use the short form.
+package alias
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Map holds a set of alias-to-real-context-name mappings, resolvable in
+// either direction.
+type Map struct {
+	toReal  map[string]string
+	toAlias map[string]string
+}
+
+// New builds a Map from alias-to-real-context-name pairs, e.g. a config
+// file's "aliases:" section.
+func New(pairs map[string]string) *Map {
+	m := &Map{toReal: make(map[string]string, len(pairs)), toAlias: make(map[string]string, len(pairs))}
+	for aliasName, real := range pairs {
+		m.Set(aliasName, real)
+	}
+	return m
+}
+
+// Set adds or overwrites a single alias-to-real-context-name mapping.
+func (m *Map) Set(aliasName, real string) {
+	m.toReal[aliasName] = real
+	m.toAlias[real] = aliasName
+}
+
+// Load reads a file of "alias=real-context-name" lines, one per line, with
+// blank lines and "#" comments ignored. A later line overrides an earlier
+// one that reuses the same alias.
+func Load(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("--context-alias-file: %w", err)
+	}
+	defer f.Close()
+
+	m := New(nil)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("--context-alias-file: invalid line %q (want alias=context-name)", line)
+		}
+		aliasName, real := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		m.Set(aliasName, real)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("--context-alias-file: %w", err)
+	}
+	return m, nil
+}
+
+// Resolve returns the real context name for an alias, and whether it was
+// found. Callers should only consult this for a name that isn't already a
+// real context: an actual context name always takes precedence over an
+// alias that happens to share its spelling.
+func (m *Map) Resolve(aliasName string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	real, ok := m.toReal[aliasName]
+	return real, ok
+}
+
+// Alias returns the alias for a real context name, and whether one is
+// configured.
+func (m *Map) Alias(realName string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	a, ok := m.toAlias[realName]
+	return a, ok
+}
+
+// Merge adds every mapping from other into m, overwriting any alias m
+// already defines for the same name. Used to layer a config file's
+// aliases: section under the (higher-precedence) --context-alias-file.
+func (m *Map) Merge(other *Map) {
+	if other == nil {
+		return
+	}
+	for aliasName, real := range other.toReal {
+		m.Set(aliasName, real)
+	}
+}
@@ -0,0 +1,125 @@
+// Package config loads kxctl's optional defaults file, which seeds
+// --include/--exclude before command-line flags are applied, so a
+// recurring choice (e.g. always excluding local clusters) doesn't need
+// retyping on every invocation.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds the default selection patterns read from the config file.
+type Config struct {
+	Include []string
+	Exclude []string
+	Aliases map[string]string
+	// Timeouts maps a context-name pattern to a duration string (e.g.
+	// "10s"), letting slow clusters get a longer --timeout than the rest
+	// without forcing every invocation onto the slowest cluster's budget.
+	Timeouts map[string]string
+}
+
+// path returns the on-disk location of the config file: $KXCTL_CONFIG if
+// set, otherwise ~/.config/kxctl/config.yaml.
+func path() (string, error) {
+	if p := os.Getenv("KXCTL_CONFIG"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "kxctl", "config.yaml"), nil
+}
+
+// Load reads and parses the config file, returning an empty Config if it
+// doesn't exist. It understands only the minimal subset of YAML kxctl's
+// config needs: top-level "include:" and "exclude:" keys, each followed by
+// "- value" list items, a top-level "aliases:" key followed by "alias:
+// real-context-name" mapping entries, and a top-level "timeouts:" key
+// followed by "pattern: duration" mapping entries, which avoids pulling in
+// a YAML dependency for what's otherwise two string lists and two string
+// maps.
+func Load() (*Config, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	cfg, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", p, err)
+	}
+	return cfg, nil
+}
+
+// parse reads include:/exclude: list keys and aliases:/timeouts: mapping
+// keys from data.
+func parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+	var currentList *[]string
+	var currentMap map[string]string
+	inAliases, inTimeouts := false, false
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		switch trimmed {
+		case "include:":
+			currentList, inAliases, inTimeouts = &cfg.Include, false, false
+			continue
+		case "exclude:":
+			currentList, inAliases, inTimeouts = &cfg.Exclude, false, false
+			continue
+		case "aliases:":
+			currentList, inAliases, inTimeouts = nil, true, false
+			continue
+		case "timeouts:":
+			currentList, inAliases, inTimeouts = nil, false, true
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			if currentList == nil {
+				return nil, fmt.Errorf("list item %q outside of an include:/exclude: key", trimmed)
+			}
+			*currentList = append(*currentList, strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			continue
+		}
+		if inAliases || inTimeouts {
+			parts := strings.SplitN(trimmed, ":", 2)
+			if len(parts) != 2 {
+				if inTimeouts {
+					return nil, fmt.Errorf("invalid timeouts entry %q (want \"pattern: duration\")", trimmed)
+				}
+				return nil, fmt.Errorf("invalid aliases entry %q (want \"alias: real-context-name\")", trimmed)
+			}
+			if inTimeouts {
+				currentMap = cfg.Timeouts
+				if currentMap == nil {
+					currentMap = make(map[string]string)
+					cfg.Timeouts = currentMap
+				}
+			} else {
+				currentMap = cfg.Aliases
+				if currentMap == nil {
+					currentMap = make(map[string]string)
+					cfg.Aliases = currentMap
+				}
+			}
+			currentMap[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			continue
+		}
+		return nil, fmt.Errorf("unrecognized line %q (only include:/exclude: keys with \"- value\" list items, and aliases:/timeouts: keys with \"key: value\" entries, are supported)", trimmed)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIncludeExclude(t *testing.T) {
+	data := []byte(`
+include:
+  - prod
+  - staging
+exclude:
+  - kind
+  - minikube
+`)
+	cfg, err := parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Include, []string{"prod", "staging"}) {
+		t.Errorf("Include = %v", cfg.Include)
+	}
+	if !reflect.DeepEqual(cfg.Exclude, []string{"kind", "minikube"}) {
+		t.Errorf("Exclude = %v", cfg.Exclude)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	cfg, err := parse(nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(cfg.Include) != 0 || len(cfg.Exclude) != 0 {
+		t.Errorf("expected empty config, got %+v", cfg)
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	data := []byte(`
+aliases:
+  prod-eu: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+  prod-us: arn:aws:eks:us-east-1:123456789012:cluster/prod
+`)
+	cfg, err := parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]string{
+		"prod-eu": "arn:aws:eks:eu-west-1:123456789012:cluster/prod",
+		"prod-us": "arn:aws:eks:us-east-1:123456789012:cluster/prod",
+	}
+	if !reflect.DeepEqual(cfg.Aliases, want) {
+		t.Errorf("Aliases = %v, want %v", cfg.Aliases, want)
+	}
+}
+
+func TestParseInvalidAliasesEntry(t *testing.T) {
+	data := []byte("aliases:\n  not-a-mapping-entry\n")
+	if _, err := parse(data); err == nil {
+		t.Error("expected error for an aliases: entry without a colon")
+	}
+}
+
+func TestParseTimeouts(t *testing.T) {
+	data := []byte(`
+timeouts:
+  prod: 10s
+  prod-slow-region: 30s
+`)
+	cfg, err := parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]string{
+		"prod":             "10s",
+		"prod-slow-region": "30s",
+	}
+	if !reflect.DeepEqual(cfg.Timeouts, want) {
+		t.Errorf("Timeouts = %v, want %v", cfg.Timeouts, want)
+	}
+}
+
+func TestParseInvalidTimeoutsEntry(t *testing.T) {
+	data := []byte("timeouts:\n  not-a-mapping-entry\n")
+	if _, err := parse(data); err == nil {
+		t.Error("expected error for a timeouts: entry without a colon")
+	}
+}
+
+func TestParseListItemOutsideKey(t *testing.T) {
+	if _, err := parse([]byte("- prod")); err == nil {
+		t.Error("expected error for list item with no preceding include:/exclude: key")
+	}
+}
+
+func TestParseUnrecognizedLine(t *testing.T) {
+	if _, err := parse([]byte("foo: bar")); err == nil {
+		t.Error("expected error for unrecognized line")
+	}
+}
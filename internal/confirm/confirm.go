@@ -0,0 +1,84 @@
+// Package confirm implements the --dry-run / --confirm-token handshake:
+// a token printed by a dry run must be echoed back on the real run before
+// a write operation is allowed to execute.
+package confirm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Key derives a stable identifier for a kubectl invocation against a set of
+// contexts, used to look up the token a prior dry run issued for it.
+func Key(kubectlArgs, targets []string) string {
+	return fmt.Sprintf("%v:%v", kubectlArgs, targets)
+}
+
+func storePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kxctl", "confirm-tokens.json"), nil
+}
+
+// Issue generates a fresh token for key and persists it, overwriting any
+// previously issued token for the same key.
+func Issue(key string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	p, err := storePath()
+	if err != nil {
+		return "", err
+	}
+	tokens, _ := load(p)
+	if tokens == nil {
+		tokens = map[string]string{}
+	}
+	tokens[key] = token
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return "", err
+	}
+	return token, os.WriteFile(p, data, 0o600)
+}
+
+// Validate reports whether token matches the most recently issued token for
+// key.
+func Validate(key, token string) (bool, error) {
+	p, err := storePath()
+	if err != nil {
+		return false, err
+	}
+	tokens, err := load(p)
+	if err != nil {
+		return false, err
+	}
+	return tokens[key] != "" && tokens[key] == token, nil
+}
+
+func load(p string) (map[string]string, error) {
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
@@ -0,0 +1,48 @@
+// Package display resolves how a context name should be shown to the user,
+// independent of the name kxctl actually passes to kubectl.
+package display
+
+import (
+	"regexp"
+
+	"github.com/mpizala/kxctl/internal/alias"
+)
+
+// Resolver rewrites context names for display only; the underlying name
+// used for execution is never affected.
+type Resolver struct {
+	trim    *regexp.Regexp
+	aliases *alias.Map
+}
+
+// NewResolver compiles a --display-trim pattern and wires in an optional
+// --context-alias-file map (nil if not in use). Every match of trimPattern
+// in a context name is removed when the name is shown to the user, unless
+// aliases has a shorter name configured for it, in which case the alias
+// wins outright.
+func NewResolver(trimPattern string, aliases *alias.Map) (*Resolver, error) {
+	r := &Resolver{aliases: aliases}
+	if trimPattern == "" {
+		return r, nil
+	}
+	re, err := regexp.Compile(trimPattern)
+	if err != nil {
+		return nil, err
+	}
+	r.trim = re
+	return r, nil
+}
+
+// Name returns how ctxName should be displayed.
+func (r *Resolver) Name(ctxName string) string {
+	if r == nil {
+		return ctxName
+	}
+	if a, ok := r.aliases.Alias(ctxName); ok {
+		return a
+	}
+	if r.trim == nil {
+		return ctxName
+	}
+	return r.trim.ReplaceAllString(ctxName, "")
+}
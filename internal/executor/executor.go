@@ -0,0 +1,1234 @@
+// Package executor runs kubectl commands against many contexts concurrently.
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mpizala/kxctl/internal/vlog"
+)
+
+// ContextResult holds the outcome of running a command against one context.
+type ContextResult struct {
+	Context  string
+	Output   string // combined stdout+stderr, preserved for the default text renderer
+	Stdout   string
+	Stderr   string
+	Err      error
+	ExitCode int
+	Duration time.Duration
+	TimedOut bool
+	// DeadlineExceeded reports that this context was cancelled by the
+	// overall deadline passed to ExecuteCommandWithRetry/Ramped/Grouped
+	// rather than by its own per-command timeout; TimedOut is left false
+	// in that case so callers can tell the two apart.
+	DeadlineExceeded bool
+	// Cancelled reports that this context was aborted by --fail-fast after
+	// a different context's command exited non-zero, rather than by its
+	// own timeout or the overall deadline; TimedOut and DeadlineExceeded
+	// are left false in that case.
+	Cancelled bool
+	Retries   int // number of retry attempts ExecuteCommandWithRetry needed before this result, 0 if it succeeded (or gave up) on the first try
+}
+
+// writeVerbs lists kubectl verbs that mutate cluster state. ExecuteCommand
+// gates these behind force unless the caller opts in.
+var writeVerbs = map[string]bool{
+	"apply":     true,
+	"create":    true,
+	"delete":    true,
+	"patch":     true,
+	"replace":   true,
+	"scale":     true,
+	"cordon":    true,
+	"uncordon":  true,
+	"drain":     true,
+	"label":     true,
+	"annotate":  true,
+	"edit":      true,
+	"rollout":   true,
+	"exec":      true,
+	"attach":    true,
+	"taint":     true,
+	"expose":    true,
+	"set":       true,
+	"autoscale": true,
+}
+
+// readOnlySubVerbs lists, per verb, the sub-verbs that only read state
+// despite the verb itself defaulting to a write (e.g. "rollout restart"
+// mutates, but "rollout status" and "rollout history" don't).
+var readOnlySubVerbs = map[string]map[string]bool{
+	"rollout": {
+		"status":  true,
+		"history": true,
+	},
+}
+
+// IsWriteOperation reports whether kubectlArgs invokes a verb that mutates
+// cluster state, by looking at the first non-flag argument. For verbs in
+// readOnlySubVerbs, it also checks the second non-flag argument and clears
+// the write flag for sub-verbs that are actually read-only, so e.g. "rollout
+// status" doesn't force users to pass --force.
+func IsWriteOperation(kubectlArgs []string) bool {
+	v := verb(kubectlArgs)
+	if !writeVerbs[v] {
+		return false
+	}
+	if readOnlySubVerbs[v][subVerb(kubectlArgs)] {
+		return false
+	}
+	return true
+}
+
+// flagsWithValue lists the global kubectl flags that consume the following
+// argument as their value, so nonFlagArgs knows to skip that token too
+// rather than mistaking it for the verb (e.g. the "foo" in "-n foo delete").
+// A flag given in "--flag=value" form never needs an entry here since it's
+// already a single "-"-prefixed token.
+var flagsWithValue = map[string]bool{
+	"-n": true, "--namespace": true,
+	"-l": true, "--selector": true,
+	"--field-selector": true,
+	"-o": true, "--output": true,
+	"--context":    true,
+	"--kubeconfig": true,
+	"--as":         true,
+	"--as-group":   true,
+	"--server":     true,
+	"--token":      true,
+	"--user":       true,
+	"--cluster":    true,
+}
+
+// nonFlagArgs returns the positional tokens of kubectlArgs with every flag,
+// and the value of any flag in flagsWithValue, removed.
+func nonFlagArgs(kubectlArgs []string) []string {
+	var out []string
+	skipNext := false
+	for _, a := range kubectlArgs {
+		if skipNext {
+			skipNext = false
+			continue
+		}
+		if len(a) > 0 && a[0] == '-' {
+			skipNext = flagsWithValue[a]
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// verb returns the first non-flag argument of kubectlArgs, which kubectl
+// treats as the operation being performed (e.g. "delete", "cordon").
+func verb(kubectlArgs []string) string {
+	args := nonFlagArgs(kubectlArgs)
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// subVerb returns the second non-flag argument of kubectlArgs, the
+// sub-command kubectl runs under verbs like "rollout" (e.g. "status" in
+// "rollout status deploy/app").
+func subVerb(kubectlArgs []string) string {
+	args := nonFlagArgs(kubectlArgs)
+	if len(args) < 2 {
+		return ""
+	}
+	return args[1]
+}
+
+// allowedWithoutForce reports whether verb appears in allowlist, so that
+// ExecuteCommand can skip the force gate for verbs the caller has decided
+// are safe in their environment even though they're normally write verbs.
+func allowedWithoutForce(v string, allowlist []string) bool {
+	for _, a := range allowlist {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultTimeoutGrace is how long ExecuteCommand waits after SIGTERM before
+// escalating to SIGKILL.
+const defaultTimeoutGrace = 2 * time.Second
+
+// ExecuteCommand runs kubectlArgs against every context in contexts, up to
+// ExecOptions groups ExecuteCommand's run parameters. It exists because
+// ExecuteCommand predates the rest of this file's ExecuteCommandWithGrace/
+// Ramped/WithRetry/Grouped family, whose positional parameter lists have
+// grown long enough that a new feature there just appends another
+// argument; ExecOptions keeps ExecuteCommand itself from following suit as
+// more of its options (Grep among them) need a home.
+type ExecOptions struct {
+	KubectlArgs []string
+	Force       bool
+	Timeout     time.Duration
+	// TimeoutOverrides, if set, replaces Timeout for any context whose name
+	// matches one of its keys (the longest matching key wins), the same way
+	// ExecuteCommandWithGrace's timeoutOverrides parameter does.
+	TimeoutOverrides map[string]time.Duration
+	// Grep, if set, filters ExecuteCommand's streamed output the same way
+	// StreamConfig.Filter does; nil disables filtering.
+	Grep        *regexp.Regexp
+	MaxParallel int
+	// Ctx, if set, additionally bounds every context's command, e.g. via
+	// SignalContext so Ctrl-C terminates in-flight kubectl processes
+	// instead of leaving them running. A nil Ctx behaves like
+	// context.Background() (never cancelled on its own).
+	Ctx context.Context
+	// Verbose, if set, logs command construction and timing for every
+	// context to stderr. A nil Verbose logs nothing.
+	Verbose *vlog.Logger
+}
+
+// RunOptions groups the run parameters shared by ExecuteCommandWithGrace,
+// ExecuteCommandRamped, ExecuteCommandWithRetry, ExecuteCommandGrouped, and
+// runFanOut. It exists for the same reason ExecOptions does: this family
+// grew a positional parameter for every later request (--fail-fast, --bin,
+// --verbose, --deadline, --retry-budget, concurrency groups, adaptive
+// backoff, ...) until several adjacent parameters shared a type and were
+// one accidental reordering away from a silent bug. The zero value runs
+// serially with no timeout, retries, or extras, the same way an empty
+// ExecOptions does for ExecuteCommand.
+type RunOptions struct {
+	KubectlArgs []string
+	// Parallel is the -p cap; see effectiveParallel for how 0 and negative
+	// values are interpreted.
+	Parallel     int
+	Timeout      time.Duration
+	TimeoutGrace time.Duration
+	// TimeoutOverrides, if set, replaces Timeout for any context whose name
+	// matches one of its keys (the longest matching key wins; see
+	// ResolveTimeout).
+	TimeoutOverrides map[string]time.Duration
+	// Ramp, if non-zero, spreads the launch of each context's command evenly
+	// over it instead of firing up to Parallel of them at once.
+	// ExecuteCommandWithGrace always runs with Ramp forced to 0.
+	Ramp time.Duration
+	// Deadline, if non-zero, bounds the total wall-clock time across every
+	// context (and, for ExecuteCommandWithRetry, every retry pass combined);
+	// contexts still outstanding when it elapses come back with
+	// DeadlineExceeded set.
+	Deadline          time.Duration
+	Force             bool
+	EchoContextEnv    bool
+	EnvOverrides      map[string][]string
+	AllowWithoutForce []string
+	AsUser            string
+	AsGroups          []string
+	Heartbeat         time.Duration
+	ProgressInterval  time.Duration
+	Adaptive          *AdaptiveErrorConfig
+	Stream            *StreamConfig
+	Verbose           *vlog.Logger
+	Kubeconfig        string
+	Bin               string
+	// FailFast, if set, cancels every other context still queued or running
+	// (reported as Cancelled) the moment any context's command exits
+	// non-zero.
+	FailFast bool
+}
+
+// kubectlBinary returns the kubectl binary to shell out to: $KXCTL_KUBECTL
+// if set, for systems where it's installed under a different name or path
+// (kubectl.exe, a wrapper script, ...), falling back to "kubectl" on PATH.
+// It only applies where a caller hasn't already chosen a binary via --bin;
+// runOne's bin parameter always takes precedence.
+func kubectlBinary() string {
+	if bin := os.Getenv("KXCTL_KUBECTL"); bin != "" {
+		return bin
+	}
+	return "kubectl"
+}
+
+// ExecuteCommand runs kubectl with opts.KubectlArgs against every context
+// in contexts, up to opts.MaxParallel at a time, and returns one
+// ContextResult per context in input order. It refuses write operations
+// unless opts.Force is true.
+//
+// The returned error reports an infrastructure-level failure (e.g. context
+// discovery); a context whose own kubectl invocation failed doesn't cause
+// one of those, but if none occurred and at least one ContextResult.Err is
+// set, ExecuteCommand returns an aggregated error counting how many
+// contexts failed, so a caller that only checks the returned error (rather
+// than walking the per-context results) still notices a partial failure.
+func ExecuteCommand(contexts []string, opts ExecOptions) ([]ContextResult, error) {
+	var stream *StreamConfig
+	if opts.Grep != nil {
+		stream = &StreamConfig{Filter: opts.Grep}
+	}
+	ctx := opts.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results, err := ExecuteCommandWithGrace(ctx, contexts, RunOptions{
+		KubectlArgs:      opts.KubectlArgs,
+		Parallel:         opts.MaxParallel,
+		Timeout:          opts.Timeout,
+		TimeoutGrace:     defaultTimeoutGrace,
+		TimeoutOverrides: opts.TimeoutOverrides,
+		Force:            opts.Force,
+		Stream:           stream,
+		Verbose:          opts.Verbose,
+		Bin:              kubectlBinary(),
+	})
+	if err != nil {
+		return results, err
+	}
+	if failed := countFailed(results); failed > 0 {
+		return results, fmt.Errorf("%d of %d contexts failed", failed, len(results))
+	}
+	return results, nil
+}
+
+// countFailed returns how many results have a per-context error set.
+func countFailed(results []ContextResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// ExecuteCommandWithGrace behaves like ExecuteCommand but takes the full
+// RunOptions: how long a timed-out command is given to exit after SIGTERM
+// before it is sent SIGKILL (opts.TimeoutGrace), whether to export the
+// target context name into each child's environment as KXCTL_CONTEXT
+// (opts.EchoContextEnv), a list of write verbs (e.g. "cordon") that should
+// bypass the force gate (opts.AllowWithoutForce), an identity to
+// impersonate via kubectl's --as/--as-group flags (opts.AsUser,
+// opts.AsGroups), per-context environment overrides (e.g. a
+// cluster-specific AWS_PROFILE or HTTPS_PROXY, keyed by context name; a
+// context absent from the map just inherits os.Environ() unchanged)
+// (opts.EnvOverrides), a heartbeat interval for CI log viewers that kill
+// jobs after a period of silent output (opts.Heartbeat), an adaptive
+// concurrency backoff config (opts.Adaptive), a stream config to print each
+// context's output line by line as it arrives instead of only once it
+// finishes (opts.Stream), a kubeconfig path (opts.Kubeconfig, "" means rely
+// on $KUBECONFIG/kubectl's default), an overall deadline capping total
+// wall-clock time across every context, independent of the per-context
+// timeout (opts.Deadline), the binary to run, e.g. "kubectl" or "helm"
+// (opts.Bin), and whether to abort every other queued or running context
+// (reported as Cancelled rather than TimedOut or a normal failure) the
+// moment any context's command exits non-zero (opts.FailFast). ctx
+// additionally bounds every context's command, e.g. via SignalContext,
+// independent of opts.Deadline. opts.Ramp is ignored (forced to 0); use
+// ExecuteCommandRamped directly to stagger launches.
+func ExecuteCommandWithGrace(ctx context.Context, contexts []string, opts RunOptions) ([]ContextResult, error) {
+	opts.Ramp = 0
+	return ExecuteCommandRamped(ctx, contexts, opts)
+}
+
+// AdaptiveErrorConfig configures the adaptive concurrency backoff used by
+// ExecuteCommandRamped and ExecuteCommandGrouped: once Window results have
+// been observed, an error rate at or above ShrinkAt reduces the effective
+// concurrency cap by one (down to Min), and a rate at or below GrowAt grows
+// it back by one (up to the run's normal -p cap), with the window reset
+// after each adjustment so one change can't immediately cascade off stale
+// data. A nil *AdaptiveErrorConfig disables adaptive behavior, leaving the
+// concurrency cap fixed at -p for the whole run.
+type AdaptiveErrorConfig struct {
+	Window   int
+	ShrinkAt float64
+	GrowAt   float64
+	Min      int
+	Verbose  bool
+}
+
+// effectiveParallel resolves the -p value a caller passed into the actual
+// concurrency cap to run with: 0 means unlimited, giving every context its
+// own goroutine slot (capped at n, since there's no point reserving more
+// slots than there are contexts to fill them); a negative value is
+// treated as the default of running serially, one at a time.
+func effectiveParallel(parallel, n int) int {
+	switch {
+	case parallel == 0:
+		return n
+	case parallel < 0:
+		return 1
+	default:
+		return parallel
+	}
+}
+
+// concurrencyGate bounds the number of in-flight kubectl invocations to a
+// limit that starts at max and, when adaptive is non-nil, shrinks or grows
+// over the life of a run in response to the recent error rate reported to
+// release.
+type concurrencyGate struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	active   int
+	limit    int
+	max      int
+	adaptive *AdaptiveErrorConfig
+	window   []bool
+}
+
+func newConcurrencyGate(max int, adaptive *AdaptiveErrorConfig) *concurrencyGate {
+	g := &concurrencyGate{limit: max, max: max, adaptive: adaptive}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// acquire blocks until a slot under the current limit is available.
+func (g *concurrencyGate) acquire() {
+	g.mu.Lock()
+	for g.active >= g.limit {
+		g.cond.Wait()
+	}
+	g.active++
+	g.mu.Unlock()
+}
+
+// release frees the caller's slot and, if adaptive backoff is enabled,
+// folds failed into the sliding window and adjusts the limit.
+func (g *concurrencyGate) release(failed bool) {
+	g.mu.Lock()
+	g.active--
+	if g.adaptive != nil {
+		g.window = append(g.window, failed)
+		if len(g.window) >= g.adaptive.Window {
+			rate := errorRate(g.window)
+			switch {
+			case rate >= g.adaptive.ShrinkAt && g.limit > g.adaptive.Min:
+				next := g.limit - 1
+				if g.adaptive.Verbose {
+					fmt.Fprintf(os.Stderr, "adaptive concurrency: error rate %.0f%% over last %d results, shrinking %d -> %d\n", rate*100, len(g.window), g.limit, next)
+				}
+				g.limit = next
+				g.window = nil
+			case rate <= g.adaptive.GrowAt && g.limit < g.max:
+				next := g.limit + 1
+				if g.adaptive.Verbose {
+					fmt.Fprintf(os.Stderr, "adaptive concurrency: error rate %.0f%% over last %d results, growing %d -> %d\n", rate*100, len(g.window), g.limit, next)
+				}
+				g.limit = next
+				g.window = nil
+			default:
+				g.window = g.window[1:]
+			}
+		}
+	}
+	g.cond.Broadcast()
+	g.mu.Unlock()
+}
+
+// errorRate returns the fraction of window that's true (a failure).
+func errorRate(window []bool) float64 {
+	n := 0
+	for _, failed := range window {
+		if failed {
+			n++
+		}
+	}
+	return float64(n) / float64(len(window))
+}
+
+// deadlineContext returns a context derived from parent, additionally bound
+// by deadline if it's non-zero (parent's own cancellation, e.g. from
+// SignalContext, still applies either way). Callers must always invoke the
+// returned cancel func to release resources, even when deadline is 0.
+func deadlineContext(parent context.Context, deadline time.Duration) (context.Context, context.CancelFunc) {
+	if deadline <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, deadline)
+}
+
+// SignalContext returns a context that's canceled on SIGINT or SIGTERM
+// (e.g. Ctrl-C), for passing as ExecuteCommandRamped's (or a sibling
+// ExecuteCommand*'s) ctx so an interrupted run terminates every in-flight
+// kubectl child process through the same graceful SIGTERM-then-SIGKILL path
+// a per-context timeout already uses, instead of leaving them running. The
+// returned stop func must be called once the context is no longer needed,
+// to restore default signal handling.
+func SignalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// ExecuteCommandRamped behaves like ExecuteCommandWithGrace but honors
+// opts.Ramp: if non-zero, it spreads the launch of each context's command
+// evenly over it, instead of firing up to opts.Parallel of them at once.
+// This smooths auth load (e.g. against an OIDC provider) beyond what the
+// concurrency cap alone achieves. ctx additionally bounds every context's
+// command, e.g. via SignalContext, independent of opts.Deadline.
+func ExecuteCommandRamped(ctx context.Context, contexts []string, opts RunOptions) ([]ContextResult, error) {
+	ctx, cancel := deadlineContext(ctx, opts.Deadline)
+	defer cancel()
+	return runFanOut(ctx, contexts, opts)
+}
+
+// runFanOut is the shared concurrency-limited, optionally-ramped worker
+// behind ExecuteCommandRamped and each retry pass of ExecuteCommandWithRetry.
+// ctx is checked by every runOne call so a single context.WithTimeout set up
+// by a caller can cancel outstanding commands across every retry pass, not
+// just the one in progress when it expires. If failFast is set, runFanOut
+// additionally derives its own cancellable context from ctx and cancels it
+// the moment any context's command exits non-zero, so every other context
+// still queued or running comes back with Cancelled set instead of running
+// to completion or its own timeout.
+func runFanOut(ctx context.Context, contexts []string, opts RunOptions) ([]ContextResult, error) {
+	v := verb(opts.KubectlArgs)
+	if IsWriteOperation(opts.KubectlArgs) && !opts.Force && !allowedWithoutForce(v, opts.AllowWithoutForce) {
+		return nil, fmt.Errorf("refusing to run write operation %q without --force", v)
+	}
+	parallel := effectiveParallel(opts.Parallel, len(contexts))
+
+	runCtx, cancelFail := context.WithCancel(ctx)
+	defer cancelFail()
+
+	results := make([]ContextResult, len(contexts))
+	var completed int64
+	stopHeartbeat := startHeartbeat(opts.Heartbeat, len(contexts), &completed)
+	defer stopHeartbeat()
+
+	if parallel == 1 {
+		// Run strictly in input order with no goroutines, no semaphore, and
+		// no stdin progress reader: output is then byte-for-byte what a
+		// hand-written serial loop would produce. Adaptive backoff has
+		// nothing to throttle when there's only one worker to begin with.
+		for i, ctxName := range contexts {
+			results[i] = runOne(runCtx, ctxName, opts, opts.EnvOverrides[ctxName])
+			atomic.AddInt64(&completed, 1)
+			if opts.FailFast && results[i].Err != nil {
+				cancelFail()
+			}
+		}
+		return results, nil
+	}
+
+	var rampStep time.Duration
+	if opts.Ramp > 0 && len(contexts) > 1 {
+		rampStep = opts.Ramp / time.Duration(len(contexts))
+	}
+
+	gate := newConcurrencyGate(parallel, opts.Adaptive)
+	var wg sync.WaitGroup
+
+	progressDone := make(chan struct{})
+	go watchProgress(progressDone, len(contexts), opts.ProgressInterval)
+
+	for i, ctxName := range contexts {
+		if rampStep > 0 && i > 0 {
+			time.Sleep(rampStep)
+		}
+		wg.Add(1)
+		opts.Verbose.Printf(2, "%s: waiting for a concurrency slot", ctxName)
+		gate.acquire()
+		opts.Verbose.Printf(2, "%s: acquired concurrency slot", ctxName)
+		go func(i int, ctxName string) {
+			defer wg.Done()
+			r := runOne(runCtx, ctxName, opts, opts.EnvOverrides[ctxName])
+			results[i] = r
+			atomic.AddInt64(&completed, 1)
+			gate.release(r.Err != nil)
+			opts.Verbose.Printf(2, "%s: released concurrency slot", ctxName)
+			if opts.FailFast && r.Err != nil {
+				cancelFail()
+			}
+		}(i, ctxName)
+	}
+
+	wg.Wait()
+	close(progressDone)
+	return results, nil
+}
+
+// startHeartbeat spawns a goroutine that prints "still running: X/Y
+// complete" to stderr every interval, reading completed under atomic
+// access so the count reflects contexts that have actually finished. It's
+// for CI log viewers that kill jobs after a period of silent output,
+// independent of the human-facing stdout and the stdin-triggered
+// watchProgress poke. An interval of 0 disables it, and the returned stop
+// function is always safe to call (a no-op when disabled).
+func startHeartbeat(interval time.Duration, total int, completed *int64) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fmt.Fprintf(os.Stderr, "still running: %d/%d complete\n", atomic.LoadInt64(completed), total)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// RetryBudget caps the total number of retries a single run is allowed to
+// spend across all contexts combined, instead of per context. This keeps a
+// widespread outage from turning into an explosion of re-auth attempts
+// against whatever's issuing credentials.
+type RetryBudget struct {
+	total     int64
+	remaining int64
+}
+
+// NewRetryBudget returns a budget that allows up to n retries in total.
+func NewRetryBudget(n int) *RetryBudget {
+	return &RetryBudget{total: int64(n), remaining: int64(n)}
+}
+
+// take atomically consumes one unit of budget and reports whether one was
+// available.
+func (b *RetryBudget) take() bool {
+	for {
+		cur := atomic.LoadInt64(&b.remaining)
+		if cur <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&b.remaining, cur, cur-1) {
+			return true
+		}
+	}
+}
+
+// Consumed reports how much of the budget has been spent so far.
+func (b *RetryBudget) Consumed() int64 {
+	return b.total - atomic.LoadInt64(&b.remaining)
+}
+
+// ExecuteCommandWithRetry behaves like ExecuteCommandRamped, but re-runs
+// opts.KubectlArgs against any context that failed, up to retries times,
+// waiting retryDelay before each retry pass (0 disables the wait). If
+// budget is non-nil, every retry attempt (across every context) must first
+// draw from it; once it's exhausted, remaining failures are no longer
+// retried even if their per-context retries aren't used up yet. Each
+// result's Retries field records how many attempts it took. opts.Deadline,
+// if non-zero, bounds the total wall-clock time of the initial pass plus
+// every retry pass combined; contexts still outstanding when it elapses
+// come back with DeadlineExceeded set instead of being retried further. If
+// opts.FailFast is set, a non-zero exit from any context cancels every
+// other context still queued or running in that pass (Cancelled set);
+// cancelled contexts are never retried, since retrying them would only race
+// the same cancellation again. ctx additionally bounds every pass, e.g. via
+// SignalContext, independent of opts.Deadline.
+func ExecuteCommandWithRetry(ctx context.Context, contexts []string, opts RunOptions, retries int, retryDelay time.Duration, budget *RetryBudget) ([]ContextResult, error) {
+	ctx, cancel := deadlineContext(ctx, opts.Deadline)
+	defer cancel()
+
+	results, err := runFanOut(ctx, contexts, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < retries; attempt++ {
+		if ctx.Err() != nil {
+			// The overall deadline already passed; further retry passes
+			// would only produce more deadline-exceeded results.
+			break
+		}
+
+		var retryIdx []int
+		var retryCtx []string
+		for i, r := range results {
+			if r.Err == nil || r.Cancelled {
+				continue
+			}
+			if budget != nil && !budget.take() {
+				continue
+			}
+			retryIdx = append(retryIdx, i)
+			retryCtx = append(retryCtx, r.Context)
+		}
+		if len(retryCtx) == 0 {
+			break
+		}
+
+		if retryDelay > 0 {
+			time.Sleep(retryDelay)
+		}
+
+		retried, err := runFanOut(ctx, retryCtx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for j, idx := range retryIdx {
+			retried[j].Retries = results[idx].Retries + 1
+			results[idx] = retried[j]
+		}
+	}
+	return results, nil
+}
+
+// ExecuteCommandGrouped behaves like ExecuteCommandRamped, but applies the
+// parallel cap within each concurrency group independently instead of
+// globally: contexts in different groups always run fully in parallel with
+// each other. A context's group is the first capture of groupPattern
+// against its name, or "default" if groupPattern is nil or doesn't match.
+// opts.Deadline, if non-zero, bounds the total wall-clock time across every
+// group combined; contexts still outstanding when it elapses come back with
+// DeadlineExceeded set. If opts.FailFast is set, a non-zero exit from any
+// context in any group cancels every other context still queued or running
+// across every group (Cancelled set), not just its own group. ctx
+// additionally bounds every group, e.g. via SignalContext, independent of
+// opts.Deadline.
+func ExecuteCommandGrouped(ctx context.Context, contexts []string, opts RunOptions, groupPattern *regexp.Regexp) ([]ContextResult, error) {
+	v := verb(opts.KubectlArgs)
+	if IsWriteOperation(opts.KubectlArgs) && !opts.Force && !allowedWithoutForce(v, opts.AllowWithoutForce) {
+		return nil, fmt.Errorf("refusing to run write operation %q without --force", v)
+	}
+	parallel := effectiveParallel(opts.Parallel, len(contexts))
+
+	ctx, cancel := deadlineContext(ctx, opts.Deadline)
+	defer cancel()
+
+	runCtx, cancelFail := context.WithCancel(ctx)
+	defer cancelFail()
+
+	results := make([]ContextResult, len(contexts))
+	var completed int64
+	stopHeartbeat := startHeartbeat(opts.Heartbeat, len(contexts), &completed)
+	defer stopHeartbeat()
+
+	if parallel == 1 {
+		for i, ctxName := range contexts {
+			results[i] = runOne(runCtx, ctxName, opts, opts.EnvOverrides[ctxName])
+			atomic.AddInt64(&completed, 1)
+			if opts.FailFast && results[i].Err != nil {
+				cancelFail()
+			}
+		}
+		return results, nil
+	}
+
+	var rampStep time.Duration
+	if opts.Ramp > 0 && len(contexts) > 1 {
+		rampStep = opts.Ramp / time.Duration(len(contexts))
+	}
+
+	// Each group gets its own concurrencyGate (and, with adaptive backoff,
+	// its own independent error window), since a burst of throttling from
+	// one auth issuer shouldn't throttle unrelated groups.
+	var gateMu sync.Mutex
+	gates := make(map[string]*concurrencyGate)
+	gateFor := func(group string) *concurrencyGate {
+		gateMu.Lock()
+		defer gateMu.Unlock()
+		gate, ok := gates[group]
+		if !ok {
+			gate = newConcurrencyGate(parallel, opts.Adaptive)
+			gates[group] = gate
+		}
+		return gate
+	}
+
+	var wg sync.WaitGroup
+	progressDone := make(chan struct{})
+	go watchProgress(progressDone, len(contexts), opts.ProgressInterval)
+
+	for i, ctxName := range contexts {
+		if rampStep > 0 && i > 0 {
+			time.Sleep(rampStep)
+		}
+		gate := gateFor(concurrencyGroup(ctxName, groupPattern))
+		wg.Add(1)
+		opts.Verbose.Printf(2, "%s: waiting for a concurrency slot", ctxName)
+		gate.acquire()
+		opts.Verbose.Printf(2, "%s: acquired concurrency slot", ctxName)
+		go func(i int, ctxName string, gate *concurrencyGate) {
+			defer wg.Done()
+			r := runOne(runCtx, ctxName, opts, opts.EnvOverrides[ctxName])
+			results[i] = r
+			atomic.AddInt64(&completed, 1)
+			gate.release(r.Err != nil)
+			opts.Verbose.Printf(2, "%s: released concurrency slot", ctxName)
+			if opts.FailFast && r.Err != nil {
+				cancelFail()
+			}
+		}(i, ctxName, gate)
+	}
+
+	wg.Wait()
+	close(progressDone)
+	return results, nil
+}
+
+// concurrencyGroup returns the concurrency group ctxName belongs to: the
+// first capture group of pattern if it matches, or "default" otherwise
+// (including when pattern is nil).
+func concurrencyGroup(ctxName string, pattern *regexp.Regexp) string {
+	if pattern == nil {
+		return "default"
+	}
+	m := pattern.FindStringSubmatch(ctxName)
+	if len(m) < 2 {
+		return "default"
+	}
+	return m[1]
+}
+
+// ProbeReachable runs a fast, read-only kubectl call against each context to
+// check whether its API server responds within probeTimeout, and returns
+// the contexts split into reachable and unreachable. It's meant to be
+// cheaper than letting a real command hang on a dead cluster until the
+// full command timeout elapses. ctx additionally bounds every probe, e.g.
+// via SignalContext.
+func ProbeReachable(ctx context.Context, contexts []string, parallel int, probeTimeout time.Duration, kubeconfig string) (reachable, unreachable []string) {
+	results, _ := ExecuteCommandRamped(ctx, contexts, RunOptions{
+		KubectlArgs:  []string{"version", "--request-timeout", probeTimeout.String()},
+		Parallel:     parallel,
+		Timeout:      probeTimeout,
+		TimeoutGrace: defaultTimeoutGrace,
+		Kubeconfig:   kubeconfig,
+		Bin:          kubectlBinary(),
+	})
+	for _, r := range results {
+		if r.Err != nil {
+			unreachable = append(unreachable, r.Context)
+		} else {
+			reachable = append(reachable, r.Context)
+		}
+	}
+	return reachable, unreachable
+}
+
+// runOne runs kubectlArgs against a single context, applying timeout as a
+// hard deadline. A command that overruns the deadline is first sent SIGTERM
+// (to its whole process group, so auth helpers and port-forward children
+// also receive it); if it hasn't exited after timeoutGrace it is sent
+// SIGKILL.
+// contextFlagByBin maps a --bin binary name to the flag it uses to select a
+// kubeconfig context, since kubectl's plugin-style alternatives don't all
+// agree on this (kubectl and helm both read the same kubeconfig, but helm
+// calls the flag --kube-context). A binary not listed here falls back to
+// kubectl's --context, which covers kubectl itself and most kubectl
+// plugins. An empty string means no context flag is added at all.
+var contextFlagByBin = map[string]string{
+	"kubectl": "--context",
+	"helm":    "--kube-context",
+}
+
+// contextFlagFor returns the context-selection flag BuildArgs should use for
+// bin, via contextFlagByBin.
+func contextFlagFor(bin string) string {
+	if flag, ok := contextFlagByBin[bin]; ok {
+		return flag
+	}
+	return "--context"
+}
+
+// BuildArgs returns the exact argument list that would be run against
+// ctxName by bin, with placeholders substituted. It's exported so callers
+// can preview a command (e.g. --dry-run) without actually running it.
+// asUser and asGroups, if set, are inserted as global --as/--as-group flags
+// ahead of the verb, so every context impersonates the same identity.
+// kubeconfig, if set, is inserted as a global --kubeconfig flag ahead of
+// the context flag, so every invocation targets the same kubeconfig file
+// regardless of $KUBECONFIG.
+func BuildArgs(ctxName string, kubectlArgs []string, asUser string, asGroups []string, kubeconfig string, bin string) []string {
+	var args []string
+	if flag := contextFlagFor(bin); flag != "" {
+		args = append(args, flag, ctxName)
+	}
+	if kubeconfig != "" {
+		args = append(args, "--kubeconfig", kubeconfig)
+	}
+	if asUser != "" {
+		args = append(args, "--as", asUser)
+	}
+	for _, g := range asGroups {
+		args = append(args, "--as-group", g)
+	}
+	return append(args, substitutePlaceholders(kubectlArgs, ctxName)...)
+}
+
+// StreamConfig enables printing a context's kubectl output line by line as
+// it arrives, instead of only once the command finishes, for progress
+// visibility on slow clusters and for tailing `logs -f`. The full output is
+// still captured in ContextResult as usual; streaming is additional, not a
+// replacement. If Filter is set, only lines matching it are printed live,
+// or only lines that do NOT match it if Invert is also set. If KeepHeader
+// is also set, the first line is always printed regardless of Filter, so a
+// kubectl column header survives a pattern that only matches data rows. If
+// Events is set, every kept line is also reported as a context-line Event,
+// alongside the context-start/context-done events runOne always sends it.
+// If Silent is set, lines are routed to Events without also being printed
+// to stdout, for callers that only want --events-ndjson, not live tailing.
+type StreamConfig struct {
+	Filter     *regexp.Regexp
+	Invert     bool
+	KeepHeader bool
+	Events     *EventSink
+	Silent     bool
+}
+
+// Event is one line of the --events-ndjson stream: a context-start when a
+// context's command begins, a context-line for each line of its live
+// output, a context-done with its exit code and duration once it finishes,
+// or a final run-summary once every context has finished. Fields that
+// don't apply to a given Type are left at their zero value and omitted.
+type Event struct {
+	Type       string `json:"type"`
+	Context    string `json:"context,omitempty"`
+	Line       string `json:"line,omitempty"`
+	ExitCode   int    `json:"exitCode,omitempty"`
+	DurationMs int64  `json:"durationMs,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	Succeeded  int    `json:"succeeded,omitempty"`
+	Failed     int    `json:"failed,omitempty"`
+}
+
+// EventSink encodes Events as newline-delimited JSON to w, one object per
+// line, serializing writes so two contexts streaming concurrently can never
+// interleave mid-line.
+type EventSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewEventSink returns an EventSink that writes to w, e.g. os.Stderr for
+// --events-ndjson.
+func NewEventSink(w io.Writer) *EventSink {
+	return &EventSink{enc: json.NewEncoder(w)}
+}
+
+func (s *EventSink) emit(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enc.Encode(e)
+}
+
+// ContextStart emits a context-start event for ctxName.
+func (s *EventSink) ContextStart(ctxName string) {
+	s.emit(Event{Type: "context-start", Context: ctxName})
+}
+
+// ContextLine emits a context-line event carrying one line of ctxName's
+// live output.
+func (s *EventSink) ContextLine(ctxName, line string) {
+	s.emit(Event{Type: "context-line", Context: ctxName, Line: line})
+}
+
+// ContextDone emits a context-done event once ctxName's command has
+// finished, successfully or not.
+func (s *EventSink) ContextDone(ctxName string, exitCode int, duration time.Duration, err error) {
+	e := Event{Type: "context-done", Context: ctxName, ExitCode: exitCode, DurationMs: duration.Milliseconds()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.emit(e)
+}
+
+// RunSummary emits the final run-summary event once every context in the
+// run has finished.
+func (s *EventSink) RunSummary(total, succeeded, failed int) {
+	s.emit(Event{Type: "run-summary", Total: total, Succeeded: succeeded, Failed: failed})
+}
+
+// streamMu serializes writes to stdout across every context streaming
+// concurrently, so two contexts' lines can never interleave mid-line.
+var streamMu sync.Mutex
+
+// streamLine prints one line of live output prefixed with its context
+// name, honoring stream.Filter and stream.Invert if set; isHeader marks the
+// first line of output, which stream.KeepHeader exempts from filtering.
+// Callers must only call it with a non-nil stream.
+func streamLine(ctxName, line string, isHeader bool, stream *StreamConfig) {
+	keep := isHeader && stream.KeepHeader
+	if !keep && stream.Filter != nil && stream.Filter.MatchString(line) == stream.Invert {
+		return
+	}
+	if stream.Events != nil {
+		stream.Events.ContextLine(ctxName, line)
+	}
+	if stream.Silent {
+		return
+	}
+	streamMu.Lock()
+	fmt.Printf("%s: %s\n", ctxName, line)
+	streamMu.Unlock()
+}
+
+// streamScan reads lines from r, accumulating them into buf (for the final
+// ContextResult) and, if stream is non-nil, printing each one live via
+// streamLine as it arrives. It must finish before the owning cmd.Wait() is
+// called, since reading the pipe is what unblocks the process's output.
+func streamScan(r io.Reader, buf *bytes.Buffer, ctxName string, stream *StreamConfig, wg *sync.WaitGroup) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if stream != nil {
+			streamLine(ctxName, line, first, stream)
+		}
+		first = false
+	}
+}
+
+// ResolveTimeout returns the duration to use for ctxName: timeout, unless
+// overrides contains one or more patterns that are a substring of ctxName,
+// in which case the override for the longest such pattern wins (ties broken
+// by map iteration order, which is fine since the repo expects distinct
+// patterns to target distinct contexts). A nil or empty overrides leaves
+// timeout unchanged.
+func ResolveTimeout(ctxName string, timeout time.Duration, overrides map[string]time.Duration) time.Duration {
+	best := ""
+	for pattern, d := range overrides {
+		if !strings.Contains(ctxName, pattern) {
+			continue
+		}
+		if len(pattern) > len(best) {
+			best = pattern
+			timeout = d
+		}
+	}
+	return timeout
+}
+
+// runOne runs opts.KubectlArgs against a single context using opts.Bin.
+// deadlineCtx is the overall run's deadline (context.Background() if the
+// caller set none); it is independent of opts.Timeout, the per-command
+// budget, so either one can cancel the command first. env, if non-empty, is
+// appended to the child's environment on top of os.Environ() (e.g. a
+// per-context AWS_PROFILE or HTTPS_PROXY); a later entry overrides an
+// earlier one with the same key, the way the environment normally works.
+// It's passed separately from opts.EnvOverrides since it's already been
+// resolved to the one context runOne is running against.
+func runOne(deadlineCtx context.Context, ctxName string, opts RunOptions, env []string) ContextResult {
+	stream := opts.Stream
+	if stream != nil && stream.Events != nil {
+		stream.Events.ContextStart(ctxName)
+	}
+	result := runOneAttempt(deadlineCtx, ctxName, opts, env)
+	if stream != nil && stream.Events != nil {
+		stream.Events.ContextDone(ctxName, result.ExitCode, result.Duration, result.Err)
+	}
+	opts.Verbose.Printf(1, "%s: finished in %s (exit %d)", ctxName, result.Duration.Round(time.Millisecond), result.ExitCode)
+	return result
+}
+
+// runOneAttempt does the actual work of runOne; split out so runOne can wrap
+// every return path with a single pair of context-start/context-done Events
+// instead of duplicating that at each of runOneAttempt's several returns.
+func runOneAttempt(deadlineCtx context.Context, ctxName string, opts RunOptions, env []string) ContextResult {
+	stream := opts.Stream
+	timeout := ResolveTimeout(ctxName, opts.Timeout, opts.TimeoutOverrides)
+	start := time.Now()
+	select {
+	case <-deadlineCtx.Done():
+		if deadlineCtx.Err() == context.Canceled {
+			return ContextResult{Context: ctxName, Err: deadlineCtx.Err(), ExitCode: 1, Duration: time.Since(start), Cancelled: true}
+		}
+		return ContextResult{Context: ctxName, Err: deadlineCtx.Err(), ExitCode: 1, Duration: time.Since(start), DeadlineExceeded: true}
+	default:
+	}
+	args := BuildArgs(ctxName, opts.KubectlArgs, opts.AsUser, opts.AsGroups, opts.Kubeconfig, opts.Bin)
+	opts.Verbose.Printf(1, "%s: %s %s", ctxName, opts.Bin, strings.Join(args, " "))
+	cmd := exec.Command(opts.Bin, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if opts.EchoContextEnv || len(env) > 0 {
+		cmdEnv := os.Environ()
+		if opts.EchoContextEnv {
+			cmdEnv = append(cmdEnv, "KXCTL_CONTEXT="+ctxName)
+		}
+		cmd.Env = append(cmdEnv, env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	var pipesDone func()
+
+	if stream == nil {
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+	} else {
+		stdoutPipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return ContextResult{Context: ctxName, Err: err, ExitCode: 1, Duration: time.Since(start)}
+		}
+		stderrPipe, err := cmd.StderrPipe()
+		if err != nil {
+			return ContextResult{Context: ctxName, Err: err, ExitCode: 1, Duration: time.Since(start)}
+		}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		pipesDone = func() {
+			go streamScan(stdoutPipe, &stdout, ctxName, stream, &wg)
+			go streamScan(stderrPipe, &stderr, ctxName, stream, &wg)
+			wg.Wait()
+		}
+	}
+
+	if err := cmd.Start(); err != nil {
+		return ContextResult{Context: ctxName, Err: err, ExitCode: 1, Duration: time.Since(start)}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		if pipesDone != nil {
+			pipesDone()
+		}
+		done <- cmd.Wait()
+	}()
+
+	var err error
+	timedOut := false
+	deadlineExceeded := false
+	cancelled := false
+	select {
+	case err = <-done:
+	case <-deadlineCtx.Done():
+		if deadlineCtx.Err() == context.Canceled {
+			cancelled = true
+		} else {
+			deadlineExceeded = true
+		}
+		err = terminateWithGrace(cmd, done, timeout, opts.TimeoutGrace)
+	case <-time.After(timeout):
+		timedOut = true
+		err = terminateWithGrace(cmd, done, timeout, opts.TimeoutGrace)
+	}
+
+	exitCode := 0
+	if err != nil {
+		exitCode = 1
+	}
+	return ContextResult{
+		Context:          ctxName,
+		Output:           stdout.String() + stderr.String(),
+		Stdout:           stdout.String(),
+		Stderr:           stderr.String(),
+		Err:              err,
+		ExitCode:         exitCode,
+		Duration:         time.Since(start),
+		DeadlineExceeded: deadlineExceeded,
+		TimedOut:         timedOut,
+		Cancelled:        cancelled,
+	}
+}
+
+// substitutePlaceholders expands "{context}" to ctxName in each argument, so
+// commands that need the context name inline (not just via the implicit
+// --context flag) can reference it. A literal brace is written as "{{".
+func substitutePlaceholders(args []string, ctxName string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		a = strings.ReplaceAll(a, "{{", "\x00")
+		a = strings.ReplaceAll(a, "}}", "\x01")
+		a = strings.ReplaceAll(a, "{context}", ctxName)
+		a = strings.ReplaceAll(a, "\x00", "{")
+		a = strings.ReplaceAll(a, "\x01", "}")
+		out[i] = a
+	}
+	return out
+}
+
+// terminateWithGrace signals cmd's process group with SIGTERM and escalates
+// to SIGKILL if it hasn't exited within grace.
+func terminateWithGrace(cmd *exec.Cmd, done <-chan error, timeout, grace time.Duration) error {
+	signalGroup(cmd, syscall.SIGTERM)
+
+	select {
+	case <-done:
+		return fmt.Errorf("timed out after %s (terminated gracefully)", timeout)
+	case <-time.After(grace):
+		signalGroup(cmd, syscall.SIGKILL)
+		<-done
+		return fmt.Errorf("timed out after %s (killed after %s grace period)", timeout, grace)
+	}
+}
+
+// signalGroup sends sig to cmd's process group, falling back to signaling
+// just the process if the group can't be resolved.
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	if pgid, err := syscall.Getpgid(cmd.Process.Pid); err == nil {
+		syscall.Kill(-pgid, sig)
+		return
+	}
+	cmd.Process.Signal(sig)
+}
+
+// watchProgress prints a progress report until done is closed, either when
+// the user presses Enter on stdin (interactive use) or every
+// progressInterval (0 disables timed reporting; non-interactive use, where
+// there's no Enter key to press). The stdin reader is only started when
+// stdin is a terminal: reading from a non-TTY stdin (a pipe, /dev/null, or a
+// backgrounded job's inherited terminal) would never see an Enter key and
+// risks leaving a goroutine blocked on a read that never returns.
+func watchProgress(done chan struct{}, total int, progressInterval time.Duration) {
+	var enter chan struct{}
+	if isTerminal(os.Stdin) {
+		enter = make(chan struct{})
+		go func() {
+			reader := bufio.NewReader(os.Stdin)
+			for {
+				if _, err := reader.ReadString('\n'); err != nil {
+					return
+				}
+				select {
+				case enter <- struct{}{}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	var tick <-chan time.Time
+	if progressInterval > 0 {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-enter:
+			printStatusReport(total)
+		case <-tick:
+			printStatusReport(total)
+		}
+	}
+}
+
+// printStatusReport prints a one-line progress update to stderr, shared by
+// watchProgress's Enter-triggered and timer-triggered reports.
+func printStatusReport(total int) {
+	fmt.Fprintf(os.Stderr, "still running against %d contexts...\n", total)
+}
+
+// isTerminal reports whether f looks like an interactive terminal, so
+// watchProgress can skip starting a blocking stdin reader when it isn't.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
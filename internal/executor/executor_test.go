@@ -0,0 +1,564 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSubstitutePlaceholders(t *testing.T) {
+	got := substitutePlaceholders([]string{"logs", "deploy/app", "--label={context}", "literal {{context}}"}, "prod-eu")
+	want := []string{"logs", "deploy/app", "--label=prod-eu", "literal {context}"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestExecuteCommandSerialOrder verifies that -p 1 produces results strictly
+// in the order contexts were given, matching a hand-written serial loop.
+func TestExecuteCommandSerialOrder(t *testing.T) {
+	t.Setenv("KXCTL_KUBECTL", "true") // stand in for kubectl, like sibling tests' "sleep"/"false" bins
+	contexts := []string{"z-context", "a-context", "m-context"}
+	results, err := ExecuteCommand(contexts, ExecOptions{KubectlArgs: []string{"--help"}, MaxParallel: 1, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("ExecuteCommand: %v", err)
+	}
+	if len(results) != len(contexts) {
+		t.Fatalf("got %d results, want %d", len(results), len(contexts))
+	}
+	for i, want := range contexts {
+		if got := results[i].Context; got != want {
+			t.Fatalf("result[%d].Context = %q, want %q", i, got, want)
+		}
+	}
+}
+
+// TestCountFailed verifies the per-context failure count ExecuteCommand
+// uses to build its aggregated error.
+func TestCountFailed(t *testing.T) {
+	results := []ContextResult{
+		{Context: "a"},
+		{Context: "b", Err: errors.New("boom")},
+		{Context: "c", Err: errors.New("boom")},
+	}
+	if got := countFailed(results); got != 2 {
+		t.Fatalf("countFailed = %d, want 2", got)
+	}
+}
+
+// TestConcurrencyGroup verifies contexts are grouped by their pattern's
+// capture, falling back to "default" when the pattern is nil or doesn't
+// match.
+func TestConcurrencyGroup(t *testing.T) {
+	pattern := regexp.MustCompile(`prod-(\w+)-`)
+	cases := []struct {
+		ctx, want string
+	}{
+		{"prod-eu-cluster1", "eu"},
+		{"prod-us-cluster2", "us"},
+		{"staging-cluster3", "default"},
+	}
+	for _, c := range cases {
+		if got := concurrencyGroup(c.ctx, pattern); got != c.want {
+			t.Errorf("concurrencyGroup(%q) = %q, want %q", c.ctx, got, c.want)
+		}
+	}
+	if got := concurrencyGroup("anything", nil); got != "default" {
+		t.Errorf("concurrencyGroup with nil pattern = %q, want %q", got, "default")
+	}
+}
+
+// TestBuildArgsImpersonation verifies --as/--as-group are inserted as
+// global flags, ahead of the verb, in the order given.
+func TestBuildArgsImpersonation(t *testing.T) {
+	got := BuildArgs("prod-eu", []string{"get", "pods"}, "system:serviceaccount:ns:sa", []string{"group-a", "group-b"}, "", "kubectl")
+	want := []string{"--context", "prod-eu", "--as", "system:serviceaccount:ns:sa", "--as-group", "group-a", "--as-group", "group-b", "get", "pods"}
+	if len(got) != len(want) {
+		t.Fatalf("BuildArgs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBuildArgsNoImpersonation verifies the --as/--as-group flags are
+// omitted entirely when no identity is given.
+func TestBuildArgsNoImpersonation(t *testing.T) {
+	got := BuildArgs("prod-eu", []string{"get", "pods"}, "", nil, "", "kubectl")
+	want := []string{"--context", "prod-eu", "get", "pods"}
+	if len(got) != len(want) {
+		t.Fatalf("BuildArgs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBuildArgsKubeconfig verifies --kubeconfig is inserted right after
+// --context, ahead of any --as/--as-group flags, and omitted when unset.
+func TestBuildArgsKubeconfig(t *testing.T) {
+	got := BuildArgs("prod-eu", []string{"get", "pods"}, "system:serviceaccount:ns:sa", []string{"group-a"}, "/tmp/kubeconfig", "kubectl")
+	want := []string{"--context", "prod-eu", "--kubeconfig", "/tmp/kubeconfig", "--as", "system:serviceaccount:ns:sa", "--as-group", "group-a", "get", "pods"}
+	if len(got) != len(want) {
+		t.Fatalf("BuildArgs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBuildArgsContextFlagByBin verifies the context-selection flag follows
+// the target binary: kubectl and unlisted binaries get --context, helm gets
+// --kube-context.
+func TestBuildArgsContextFlagByBin(t *testing.T) {
+	cases := []struct {
+		bin  string
+		flag string
+	}{
+		{"kubectl", "--context"},
+		{"helm", "--kube-context"},
+		{"some-other-plugin", "--context"},
+	}
+	for _, c := range cases {
+		got := BuildArgs("prod-eu", []string{"list"}, "", nil, "", c.bin)
+		if len(got) < 2 || got[0] != c.flag || got[1] != "prod-eu" {
+			t.Errorf("BuildArgs(bin=%q) = %v, want to start with [%q prod-eu]", c.bin, got, c.flag)
+		}
+	}
+}
+
+// TestIsWriteOperationRolloutSubcommands verifies that read-only rollout
+// sub-verbs (status, history) don't trip the write gate, while mutating ones
+// (restart, undo) and plain "rollout" still do.
+func TestIsWriteOperationRolloutSubcommands(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"status", []string{"rollout", "status", "deploy/app"}, false},
+		{"history", []string{"rollout", "history", "deploy/app"}, false},
+		{"restart", []string{"rollout", "restart", "deploy/app"}, true},
+		{"undo", []string{"rollout", "undo", "deploy/app"}, true},
+		{"bare", []string{"rollout"}, true},
+	}
+	for _, c := range cases {
+		if got := IsWriteOperation(c.args); got != c.want {
+			t.Errorf("IsWriteOperation(%q) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestIsWriteOperationSkipsLeadingFlags verifies the write-verb check finds
+// the verb even when it's preceded by global flags, including ones like
+// -n/--namespace whose value is a separate argument that isn't itself a
+// flag and so could be mistaken for the verb.
+func TestIsWriteOperationSkipsLeadingFlags(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"namespace short flag", []string{"-n", "foo", "delete", "pod", "x"}, true},
+		{"namespace long flag", []string{"--namespace", "foo", "delete", "pod", "x"}, true},
+		{"namespace equals form", []string{"--namespace=foo", "delete", "pod", "x"}, true},
+		{"multiple flags", []string{"--context", "prod", "-n", "foo", "delete", "pod", "x"}, true},
+		{"boolean flag then read verb", []string{"-n", "foo", "get", "pods"}, false},
+	}
+	for _, c := range cases {
+		if got := IsWriteOperation(c.args); got != c.want {
+			t.Errorf("IsWriteOperation(%q) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestRetryBudgetExhausts verifies that a RetryBudget stops handing out
+// retries once its total is consumed, and reports how much was used.
+func TestRetryBudgetExhausts(t *testing.T) {
+	b := NewRetryBudget(2)
+	if !b.take() || !b.take() {
+		t.Fatal("expected the first two take() calls to succeed")
+	}
+	if b.take() {
+		t.Fatal("expected take() to fail once the budget is exhausted")
+	}
+	if got := b.Consumed(); got != 2 {
+		t.Fatalf("Consumed() = %d, want 2", got)
+	}
+}
+
+// TestTerminateWithGrace verifies that a process trapping SIGTERM is given
+// the grace period to exit before being escalated to SIGKILL.
+func TestTerminateWithGrace(t *testing.T) {
+	cmd := exec.Command("sh", "-c", `trap 'exit 0' TERM; sleep 5 & wait`)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	start := time.Now()
+	terminateWithGrace(cmd, done, 0, 500*time.Millisecond)
+	elapsed := time.Since(start)
+
+	if elapsed >= 500*time.Millisecond {
+		t.Fatalf("expected process to exit promptly after SIGTERM, took %s", elapsed)
+	}
+}
+
+// TestTerminateWithGraceEscalatesToKill verifies a process that ignores
+// SIGTERM is force-killed once the grace period elapses.
+func TestTerminateWithGraceEscalatesToKill(t *testing.T) {
+	cmd := exec.Command("sh", "-c", `trap '' TERM; echo trapped; sleep 5`)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	// Wait for the shell to report the trap is actually installed before
+	// signaling it; otherwise SIGTERM can race the trap and kill the shell
+	// via its default disposition well within the grace window, which
+	// would make this test pass for the wrong reason.
+	if _, err := bufio.NewReader(stdout).ReadString('\n'); err != nil {
+		t.Fatalf("waiting for trap: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	start := time.Now()
+	terminateWithGrace(cmd, done, 0, 200*time.Millisecond)
+	elapsed := time.Since(start)
+
+	if elapsed < 200*time.Millisecond {
+		t.Fatalf("expected SIGKILL to wait for the grace period, took %s", elapsed)
+	}
+}
+
+// TestTerminateWithGraceReportsTimeoutMessage verifies a process killed for
+// running past its timeout comes back with a "timed out after" message
+// rather than a generic one, regardless of what its OS exit state looks
+// like once killed.
+func TestTerminateWithGraceReportsTimeoutMessage(t *testing.T) {
+	cmd := exec.Command("sleep", "5")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	err := terminateWithGrace(cmd, done, 50*time.Millisecond, 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out after") {
+		t.Fatalf("terminateWithGrace error = %v, want a message containing %q", err, "timed out after")
+	}
+}
+
+// TestRunOneNonExistentBinary verifies that a bin which fails to start (no
+// such executable) comes back as a clean ContextResult.Err from cmd.Start(),
+// rather than reaching the timeout/kill path at all.
+func TestRunOneNonExistentBinary(t *testing.T) {
+	result := runOne(context.Background(), "prod-eu", RunOptions{KubectlArgs: []string{"get", "pods"}, Timeout: time.Second, TimeoutGrace: time.Second, Bin: "kxctl-no-such-binary"}, nil)
+	if result.Err == nil {
+		t.Fatal("expected an error for a non-existent binary, got nil")
+	}
+	if result.TimedOut || result.DeadlineExceeded {
+		t.Fatalf("expected a start failure, not a timeout: %+v", result)
+	}
+}
+
+// TestSignalGroupNilProcess verifies signalGroup tolerates a *exec.Cmd whose
+// Process was never set, e.g. one that failed cmd.Start(), instead of
+// panicking on a nil pointer dereference.
+func TestSignalGroupNilProcess(t *testing.T) {
+	cmd := exec.Command("kxctl-no-such-binary")
+	signalGroup(cmd, syscall.SIGTERM)
+}
+
+// TestRunOneCancelledContext verifies runOne reports Cancelled, not
+// DeadlineExceeded, when its context was cancelled explicitly (--fail-fast)
+// rather than timed out via context.WithTimeout.
+func TestRunOneCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := runOne(ctx, "prod-eu", RunOptions{KubectlArgs: []string{"get", "pods"}, Timeout: time.Second, TimeoutGrace: time.Second, Bin: "kubectl"}, nil)
+	if !result.Cancelled {
+		t.Fatalf("expected Cancelled=true, got %+v", result)
+	}
+	if result.DeadlineExceeded || result.TimedOut {
+		t.Fatalf("expected only Cancelled to be set, got %+v", result)
+	}
+}
+
+// TestRunFanOutFailFastCancelsRemaining verifies that with failFast set, a
+// failing context (bin "false" always exits non-zero) cancels every
+// context queued after it, and that runFanOut still returns promptly
+// instead of running them to completion.
+func TestRunFanOutFailFastCancelsRemaining(t *testing.T) {
+	contexts := []string{"a", "b", "c"}
+	results, err := runFanOut(context.Background(), contexts, RunOptions{Parallel: 1, Timeout: time.Second, TimeoutGrace: time.Second, Bin: "false", FailFast: true})
+	if err != nil {
+		t.Fatalf("runFanOut: %v", err)
+	}
+	if results[0].Cancelled {
+		t.Fatalf("expected the first context to actually run, got cancelled: %+v", results[0])
+	}
+	if results[0].Err == nil {
+		t.Fatal("expected the first context to fail (bin \"false\"), got no error")
+	}
+	for i := 1; i < len(results); i++ {
+		if !results[i].Cancelled {
+			t.Fatalf("expected context %d to be cancelled after fail-fast, got %+v", i, results[i])
+		}
+	}
+}
+
+// TestEffectiveParallel verifies 0 resolves to n (unlimited, capped at the
+// number of contexts), a negative value falls back to the serial default
+// of 1, and a positive value passes through unchanged.
+func TestEffectiveParallel(t *testing.T) {
+	cases := []struct {
+		parallel, n, want int
+	}{
+		{0, 5, 5},
+		{0, 0, 0},
+		{-1, 5, 1},
+		{3, 5, 3},
+	}
+	for _, c := range cases {
+		if got := effectiveParallel(c.parallel, c.n); got != c.want {
+			t.Errorf("effectiveParallel(%d, %d) = %d, want %d", c.parallel, c.n, got, c.want)
+		}
+	}
+}
+
+// TestResolveTimeout verifies a context matching multiple override patterns
+// uses the longest (most specific) one, falls back to the global timeout
+// when nothing matches, and passes through unchanged for a nil map.
+func TestResolveTimeout(t *testing.T) {
+	overrides := map[string]time.Duration{
+		"prod":      5 * time.Second,
+		"prod-slow": 30 * time.Second,
+	}
+	if got := ResolveTimeout("prod-slow-eu", time.Second, overrides); got != 30*time.Second {
+		t.Errorf("ResolveTimeout(prod-slow-eu) = %v, want 30s", got)
+	}
+	if got := ResolveTimeout("prod-us", time.Second, overrides); got != 5*time.Second {
+		t.Errorf("ResolveTimeout(prod-us) = %v, want 5s", got)
+	}
+	if got := ResolveTimeout("staging", time.Second, overrides); got != time.Second {
+		t.Errorf("ResolveTimeout(staging) = %v, want unchanged 1s", got)
+	}
+	if got := ResolveTimeout("staging", time.Second, nil); got != time.Second {
+		t.Errorf("ResolveTimeout(nil overrides) = %v, want unchanged 1s", got)
+	}
+}
+
+// TestRunFanOutUnlimitedParallel verifies parallel=0 still runs every
+// context (not just one), exercising the unlimited path end to end rather
+// than just effectiveParallel's arithmetic.
+func TestRunFanOutUnlimitedParallel(t *testing.T) {
+	contexts := []string{"a", "b", "c", "d"}
+	results, err := runFanOut(context.Background(), contexts, RunOptions{KubectlArgs: []string{"--help"}, Parallel: 0, Timeout: 5 * time.Second, TimeoutGrace: time.Second, Bin: "kubectl"})
+	if err != nil {
+		t.Fatalf("runFanOut: %v", err)
+	}
+	if len(results) != len(contexts) {
+		t.Fatalf("got %d results, want %d", len(results), len(contexts))
+	}
+}
+
+// TestWatchProgressTimerReportsWithoutEnter verifies watchProgress prints a
+// report on its own via progressInterval, with no Enter keypress needed.
+func TestWatchProgressTimerReportsWithoutEnter(t *testing.T) {
+	old := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	os.Stderr = w
+	defer func() { os.Stderr = old }()
+
+	done := make(chan struct{})
+	go watchProgress(done, 3, 20*time.Millisecond)
+
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	close(done)
+	w.Close()
+	os.Stderr = old
+
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got := string(buf[:n]); !strings.Contains(got, "still running against 3 contexts") {
+		t.Fatalf("output = %q, want it to contain the progress report", got)
+	}
+}
+
+// TestIsTerminalFalseForPipe verifies isTerminal reports false for a plain
+// os.Pipe, which is the non-TTY case watchProgress needs to detect in order
+// to skip its blocking stdin reader.
+func TestIsTerminalFalseForPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if isTerminal(r) {
+		t.Fatal("isTerminal(pipe) = true, want false")
+	}
+}
+
+// TestStartHeartbeatDisabled verifies an interval of 0 spawns nothing and
+// returns a stop func that's always safe to call.
+func TestStartHeartbeatDisabled(t *testing.T) {
+	var completed int64
+	stop := startHeartbeat(0, 3, &completed)
+	stop()
+}
+
+// TestStartHeartbeatStopsPromptly verifies stop() halts the ticking goroutine
+// without waiting for another tick to fire.
+func TestStartHeartbeatStopsPromptly(t *testing.T) {
+	var completed int64
+	atomic.StoreInt64(&completed, 1)
+	stop := startHeartbeat(50*time.Millisecond, 3, &completed)
+
+	start := time.Now()
+	stop()
+	elapsed := time.Since(start)
+
+	if elapsed >= 50*time.Millisecond {
+		t.Fatalf("expected stop() to return promptly, took %s", elapsed)
+	}
+}
+
+// TestConcurrencyGateShrinksAndGrows verifies the gate reduces its limit
+// once a window's worth of results come back failing, and grows it back
+// once a window of results comes back clean.
+func TestConcurrencyGateShrinksAndGrows(t *testing.T) {
+	gate := newConcurrencyGate(4, &AdaptiveErrorConfig{Window: 3, ShrinkAt: 0.5, GrowAt: 0, Min: 1})
+
+	for i := 0; i < 3; i++ {
+		gate.acquire()
+		gate.release(true)
+	}
+	if gate.limit != 3 {
+		t.Fatalf("limit after 3 failures = %d, want 3", gate.limit)
+	}
+
+	for i := 0; i < 3; i++ {
+		gate.acquire()
+		gate.release(false)
+	}
+	if gate.limit != 4 {
+		t.Fatalf("limit after 3 successes = %d, want 4", gate.limit)
+	}
+}
+
+// TestStreamScanAccumulatesAllLines verifies streamScan collects every line
+// into buf regardless of stream's filter, since the filter only controls
+// what's printed live, not what's captured in the final result.
+func TestStreamScanAccumulatesAllLines(t *testing.T) {
+	var buf bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	stream := &StreamConfig{Filter: regexp.MustCompile(`keep`)}
+	streamScan(strings.NewReader("keep this\nskip this\nkeep that\n"), &buf, "ctx", stream, &wg)
+	wg.Wait()
+
+	want := "keep this\nskip this\nkeep that\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("buf = %q, want %q", got, want)
+	}
+}
+
+// TestStreamScanNilStream verifies streamScan still accumulates into buf
+// when streaming itself is disabled (stream is nil).
+func TestStreamScanNilStream(t *testing.T) {
+	var buf bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	streamScan(strings.NewReader("a\nb\n"), &buf, "ctx", nil, &wg)
+	wg.Wait()
+
+	if got := buf.String(); got != "a\nb\n" {
+		t.Fatalf("buf = %q, want %q", got, "a\nb\n")
+	}
+}
+
+// TestConcurrencyGateRespectsMin verifies the gate never shrinks below Min.
+func TestConcurrencyGateRespectsMin(t *testing.T) {
+	gate := newConcurrencyGate(2, &AdaptiveErrorConfig{Window: 2, ShrinkAt: 0.5, GrowAt: 0, Min: 1})
+
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 2; i++ {
+			gate.acquire()
+			gate.release(true)
+		}
+	}
+	if gate.limit != 1 {
+		t.Fatalf("limit = %d, want 1 (Min)", gate.limit)
+	}
+}
+
+// TestExecuteCommandRampedParentCancellation verifies that cancelling the
+// ctx passed into ExecuteCommandRamped (what SignalContext returns on
+// SIGINT/SIGTERM) terminates an in-flight long-running command well before
+// its own --timeout would, reporting it as Cancelled rather than
+// DeadlineExceeded. "sleep 5" stands in for a slow kubectl invocation.
+func TestExecuteCommandRampedParentCancellation(t *testing.T) {
+	// "sleep" is just a stand-in for a slow kubectl invocation here, not a
+	// real --bin target, so it shouldn't get a real --context flag it
+	// doesn't understand; register it in contextFlagByBin for the
+	// duration of this test only, rather than baking test binary names
+	// into the production map real --bin users rely on.
+	contextFlagByBin["sleep"] = ""
+	t.Cleanup(func() { delete(contextFlagByBin, "sleep") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	results, err := ExecuteCommandRamped(ctx, []string{"prod-eu"}, RunOptions{KubectlArgs: []string{"5"}, Parallel: 1, Timeout: 10 * time.Second, TimeoutGrace: 200 * time.Millisecond, Bin: "sleep"})
+	if err != nil {
+		t.Fatalf("ExecuteCommandRamped: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("took %v, want well under the 10s --timeout (parent cancellation should cut it short)", elapsed)
+	}
+	if len(results) != 1 || !results[0].Cancelled || results[0].Err == nil {
+		t.Fatalf("results = %+v, want a single Cancelled result with an error", results)
+	}
+}
@@ -0,0 +1,284 @@
+// Package filter selects context names based on include/exclude patterns.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// matchPattern reports whether name matches pattern. A pattern containing
+// "&" (or starting with "!") is a compound AND/NOT expression evaluated by
+// matchCompound; otherwise a pattern containing "*" or "?" is matched as a
+// shell-style glob against the whole name (full anchoring, via
+// path.Match), and anything else is a plain substring check. With fuzzy
+// set, glob detection is skipped in favor of a subsequence check
+// (pattern's characters appear in name in order, not necessarily
+// contiguous), the way fzf matches by default; fuzzy applies per term
+// inside a compound expression too. With ignoreCase set, both name and
+// pattern are lowercased first, so casing differences in either one never
+// prevent a match.
+func matchPattern(name, pattern string, fuzzy, ignoreCase bool) bool {
+	if ignoreCase {
+		name = strings.ToLower(name)
+		pattern = strings.ToLower(pattern)
+	}
+	if isCompound(pattern) {
+		return matchCompound(name, pattern, fuzzy)
+	}
+	return matchSimple(name, pattern, fuzzy)
+}
+
+// isCompound reports whether pattern should be evaluated as an AND/NOT
+// expression rather than matched directly.
+func isCompound(pattern string) bool {
+	return strings.Contains(pattern, "&") || strings.HasPrefix(pattern, "!")
+}
+
+// matchCompound evaluates an "&"-separated AND expression such as
+// "prod&!eu" (contains "prod" and does not contain "eu"). Every "&"-joined
+// term must match (after stripping a leading "!", which negates that
+// term's result) for the whole pattern to match; there's no "|" (OR) or
+// parentheses, so precedence is simply left-to-right across the terms, all
+// of which are required. Each term is matched via matchSimple, so a glob
+// or fuzzy term composes with a negated substring term, e.g. "*-eu&!canary".
+func matchCompound(name, pattern string, fuzzy bool) bool {
+	for _, term := range strings.Split(pattern, "&") {
+		negate := strings.HasPrefix(term, "!")
+		if negate {
+			term = term[1:]
+		}
+		matched := matchSimple(name, term, fuzzy)
+		if negate {
+			matched = !matched
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSimple is matchPattern's non-compound case: a glob, fuzzy
+// subsequence, or plain substring match, in that priority order.
+func matchSimple(name, pattern string, fuzzy bool) bool {
+	if fuzzy {
+		return isSubsequence(pattern, name)
+	}
+	if isGlob(pattern) {
+		matched, err := path.Match(pattern, name)
+		return err == nil && matched
+	}
+	return strings.Contains(name, pattern)
+}
+
+// isGlob reports whether pattern should be matched as a shell-style glob
+// rather than a substring.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
+
+// isSubsequence reports whether every character of pattern appears in name,
+// in order. kxctl has no interactive UI to rank matches by score, so unlike
+// fzf this is a plain yes/no test rather than a scored one.
+func isSubsequence(pattern, name string) bool {
+	pi := 0
+	for i := 0; i < len(name) && pi < len(pattern); i++ {
+		if name[i] == pattern[pi] {
+			pi++
+		}
+	}
+	return pi == len(pattern)
+}
+
+// Apply returns the subset of contexts that match the include patterns
+// (or all contexts, if no include patterns are given) and none of the
+// exclude patterns. By default a context need only match at least one
+// include pattern; with includeAll set, it must match every one of them
+// instead (AND semantics). Exclude patterns are always "match any",
+// regardless of includeAll.
+func Apply(contexts []string, include, exclude []string, fuzzy, ignoreCase, includeAll bool) []string {
+	var result []string
+	for _, c := range contexts {
+		if len(include) > 0 && !matchesInclude(c, include, fuzzy, ignoreCase, includeAll) {
+			continue
+		}
+		if matchesAny(c, exclude, fuzzy, ignoreCase) {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// UnmatchedIncludes returns the include patterns that didn't match any of
+// contexts, so a caller can warn about a likely typo (e.g. "-i prduction")
+// instead of only reporting the filtered set came up empty overall. It
+// checks each pattern independently of includeAll, since a pattern that
+// matches nothing at all is worth flagging whether include is OR'd or
+// AND'd together.
+func UnmatchedIncludes(contexts []string, include []string, fuzzy, ignoreCase bool) []string {
+	var unmatched []string
+	for _, p := range include {
+		if !anyMatches(contexts, p, fuzzy, ignoreCase) {
+			unmatched = append(unmatched, p)
+		}
+	}
+	return unmatched
+}
+
+// anyMatches reports whether pattern matches at least one of contexts.
+func anyMatches(contexts []string, pattern string, fuzzy, ignoreCase bool) bool {
+	for _, c := range contexts {
+		if matchPattern(c, pattern, fuzzy, ignoreCase) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesInclude applies include's OR or AND semantics depending on
+// includeAll.
+func matchesInclude(name string, include []string, fuzzy, ignoreCase, includeAll bool) bool {
+	if includeAll {
+		return matchesAll(name, include, fuzzy, ignoreCase)
+	}
+	return matchesAny(name, include, fuzzy, ignoreCase)
+}
+
+// MatchSpan returns the [start, end) byte range of pattern's first match in
+// name, and whether it matched at all. It shares matchPattern's matching
+// semantics so highlighting stays consistent with filtering; with fuzzy
+// set, the range spans from the first matched character to the last. A
+// glob pattern either matches the whole name or doesn't match at all, so
+// its span is the full string. With ignoreCase set, the returned span
+// still indexes into the original (not lowercased) name.
+func MatchSpan(name, pattern string, fuzzy, ignoreCase bool) (start, end int, ok bool) {
+	cmpName, cmpPattern := name, pattern
+	if ignoreCase {
+		cmpName = strings.ToLower(name)
+		cmpPattern = strings.ToLower(pattern)
+	}
+	if fuzzy {
+		return fuzzySpan(cmpPattern, cmpName)
+	}
+	if isGlob(cmpPattern) {
+		matched, err := path.Match(cmpPattern, cmpName)
+		if err != nil || !matched {
+			return 0, 0, false
+		}
+		return 0, len(name), true
+	}
+	idx := strings.Index(cmpName, cmpPattern)
+	if idx == -1 {
+		return 0, 0, false
+	}
+	return idx, idx + len(cmpPattern), true
+}
+
+// fuzzySpan finds the byte range in name from the first character matched
+// by pattern's subsequence to the last, or reports no match.
+func fuzzySpan(pattern, name string) (start, end int, ok bool) {
+	pi := 0
+	first, last := -1, -1
+	for i := 0; i < len(name) && pi < len(pattern); i++ {
+		if name[i] == pattern[pi] {
+			if first == -1 {
+				first = i
+			}
+			last = i
+			pi++
+		}
+	}
+	if pi != len(pattern) {
+		return 0, 0, false
+	}
+	return first, last + 1, true
+}
+
+// Decision records why Apply did or didn't select a context, for
+// --explain-filter debugging.
+type Decision struct {
+	Context        string
+	Selected       bool
+	MatchedInclude string // include pattern that matched, if any
+	MatchedExclude string // exclude pattern that rejected it, if any
+}
+
+// Explain evaluates the same rules as Apply but returns the reasoning
+// behind each context's inclusion or exclusion instead of just the
+// filtered list. With includeAll set, MatchedInclude reports every
+// pattern (joined with " & "), since no single one of them decided the
+// match.
+func Explain(contexts []string, include, exclude []string, fuzzy, ignoreCase, includeAll bool) []Decision {
+	decisions := make([]Decision, 0, len(contexts))
+	for _, c := range contexts {
+		d := Decision{Context: c}
+
+		if len(include) > 0 {
+			matched := false
+			if includeAll {
+				if matchesAll(c, include, fuzzy, ignoreCase) {
+					d.MatchedInclude = strings.Join(include, " & ")
+					matched = true
+				}
+			} else {
+				for _, p := range include {
+					if matchPattern(c, p, fuzzy, ignoreCase) {
+						d.MatchedInclude = p
+						matched = true
+						break
+					}
+				}
+			}
+			if !matched {
+				decisions = append(decisions, d)
+				continue
+			}
+		}
+
+		excluded := false
+		for _, p := range exclude {
+			if matchPattern(c, p, fuzzy, ignoreCase) {
+				d.MatchedExclude = p
+				excluded = true
+				break
+			}
+		}
+		d.Selected = !excluded
+		decisions = append(decisions, d)
+	}
+	return decisions
+}
+
+// Only returns the single context exactly named name, erroring if it isn't
+// among contexts. Unlike Apply's include patterns, this is an exact match,
+// so it can't accidentally select more than one context.
+func Only(contexts []string, name string) ([]string, error) {
+	for _, c := range contexts {
+		if c == name {
+			return []string{c}, nil
+		}
+	}
+	return nil, fmt.Errorf("no context named %q", name)
+}
+
+func matchesAny(name string, patterns []string, fuzzy, ignoreCase bool) bool {
+	for _, p := range patterns {
+		if matchPattern(name, p, fuzzy, ignoreCase) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAll reports whether name matches every one of patterns, for
+// --include-all's AND semantics.
+func matchesAll(name string, patterns []string, fuzzy, ignoreCase bool) bool {
+	for _, p := range patterns {
+		if !matchPattern(name, p, fuzzy, ignoreCase) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package filter
+
+import "testing"
+
+// TestApplyIncludeAll verifies --include-all's AND semantics: a context
+// must match every include pattern, not just one, while exclude stays
+// "match any".
+func TestApplyIncludeAll(t *testing.T) {
+	contexts := []string{"prod-eu-cluster1", "prod-us-cluster2", "staging-eu"}
+
+	got := Apply(contexts, []string{"prod", "eu"}, nil, false, false, true)
+	want := []string{"prod-eu-cluster1"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("Apply(include-all prod,eu) = %v, want %v", got, want)
+	}
+
+	// Without includeAll, the same two patterns OR together and match
+	// every context above.
+	got = Apply(contexts, []string{"prod", "eu"}, nil, false, false, false)
+	if len(got) != len(contexts) {
+		t.Fatalf("Apply(prod,eu) = %v, want all %d contexts", got, len(contexts))
+	}
+}
+
+func TestUnmatchedIncludes(t *testing.T) {
+	contexts := []string{"prod-eu", "prod-us", "staging"}
+
+	got := UnmatchedIncludes(contexts, []string{"prod", "prduction"}, false, false)
+	want := []string{"prduction"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("UnmatchedIncludes() = %v, want %v", got, want)
+	}
+
+	if got := UnmatchedIncludes(contexts, []string{"prod", "staging"}, false, false); len(got) != 0 {
+		t.Fatalf("UnmatchedIncludes() = %v, want none", got)
+	}
+}
+
+func TestOnly(t *testing.T) {
+	contexts := []string{"prod-us", "prod-eu", "staging"}
+
+	got, err := Only(contexts, "prod-eu")
+	if err != nil {
+		t.Fatalf("Only: %v", err)
+	}
+	if len(got) != 1 || got[0] != "prod-eu" {
+		t.Fatalf("Only(prod-eu) = %v, want [prod-eu]", got)
+	}
+
+	if _, err := Only(contexts, "does-not-exist"); err == nil {
+		t.Fatal("expected error for missing context, got nil")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	contexts := []string{"prod-us", "prod-eu", "staging"}
+
+	decisions := Explain(contexts, []string{"prod"}, []string{"eu"}, false, false, false)
+	want := map[string]Decision{
+		"prod-us": {Context: "prod-us", Selected: true, MatchedInclude: "prod"},
+		"prod-eu": {Context: "prod-eu", Selected: false, MatchedInclude: "prod", MatchedExclude: "eu"},
+		"staging": {Context: "staging", Selected: false},
+	}
+	for _, d := range decisions {
+		if d != want[d.Context] {
+			t.Errorf("Explain(%s) = %+v, want %+v", d.Context, d, want[d.Context])
+		}
+	}
+}
+
+func TestApplyFuzzy(t *testing.T) {
+	contexts := []string{"prod-eu-cluster1", "prod-us-cluster2", "staging-eu"}
+
+	got := Apply(contexts, []string{"pdeu"}, nil, true, false, false)
+	want := []string{"prod-eu-cluster1"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("Apply(fuzzy pdeu) = %v, want %v", got, want)
+	}
+
+	if got := Apply(contexts, []string{"zzz"}, nil, true, false, false); len(got) != 0 {
+		t.Fatalf("Apply(fuzzy zzz) = %v, want no matches", got)
+	}
+
+	// "pdus" is a subsequence of prod-us-cluster2 but the letters must stay
+	// in order, so "usdp" (reversed) should not match anything.
+	if got := Apply(contexts, []string{"usdp"}, nil, true, false, false); len(got) != 0 {
+		t.Fatalf("Apply(fuzzy usdp) = %v, want no matches (out-of-order subsequence)", got)
+	}
+}
+
+func TestMatchSpanFuzzy(t *testing.T) {
+	start, end, ok := MatchSpan("prod-eu-cluster1", "pdeu", true, false)
+	if !ok {
+		t.Fatal("expected a fuzzy match")
+	}
+	if got := "prod-eu-cluster1"[start:end]; got != "prod-eu" {
+		t.Errorf("fuzzy span = %q, want %q", got, "prod-eu")
+	}
+
+	if _, _, ok := MatchSpan("prod-eu-cluster1", "zzz", true, false); ok {
+		t.Error("expected no fuzzy match for zzz")
+	}
+}
+
+func TestApplyGlob(t *testing.T) {
+	contexts := []string{"prod-eu", "prod-us", "staging-eu"}
+
+	got := Apply(contexts, []string{"prod-*"}, nil, false, false, false)
+	want := []string{"prod-eu", "prod-us"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Apply(prod-*) = %v, want %v", got, want)
+	}
+
+	got = Apply(contexts, []string{"*-eu"}, nil, false, false, false)
+	want = []string{"prod-eu", "staging-eu"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Apply(*-eu) = %v, want %v", got, want)
+	}
+
+	// A glob is anchored to the whole name, so a pattern matching only a
+	// fragment shouldn't hit like a substring check would.
+	if got := Apply(contexts, []string{"prod-e?"}, nil, false, false, false); len(got) != 1 || got[0] != "prod-eu" {
+		t.Fatalf("Apply(prod-e?) = %v, want [prod-eu]", got)
+	}
+}
+
+// TestApplyIgnoreCase verifies a mixed-case substring pattern only matches
+// a differently-cased context when ignoreCase is set; the default stays
+// case-sensitive.
+func TestApplyIgnoreCase(t *testing.T) {
+	contexts := []string{"Prod-EU", "prod-us", "staging"}
+
+	if got := Apply(contexts, []string{"prod"}, nil, false, false, false); len(got) != 1 || got[0] != "prod-us" {
+		t.Fatalf("Apply(prod, case-sensitive) = %v, want [prod-us]", got)
+	}
+
+	got := Apply(contexts, []string{"prod"}, nil, false, true, false)
+	want := []string{"Prod-EU", "prod-us"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Apply(prod, ignoreCase) = %v, want %v", got, want)
+	}
+}
+
+// TestApplyCompound verifies the "&"/"!" AND/NOT mini-syntax: "prod&!eu"
+// requires "prod" and rejects "eu", "prod&us" requires both substrings,
+// and "!test" matches any name that doesn't contain "test" on its own.
+func TestApplyCompound(t *testing.T) {
+	contexts := []string{"prod-eu", "prod-us", "staging-eu", "test-us"}
+
+	got := Apply(contexts, []string{"prod&!eu"}, nil, false, false, false)
+	if len(got) != 1 || got[0] != "prod-us" {
+		t.Fatalf("Apply(prod&!eu) = %v, want [prod-us]", got)
+	}
+
+	got = Apply(contexts, []string{"prod&us"}, nil, false, false, false)
+	if len(got) != 1 || got[0] != "prod-us" {
+		t.Fatalf("Apply(prod&us) = %v, want [prod-us]", got)
+	}
+
+	got = Apply(contexts, []string{"!test"}, nil, false, false, false)
+	want := []string{"prod-eu", "prod-us", "staging-eu"}
+	if len(got) != len(want) {
+		t.Fatalf("Apply(!test) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Apply(!test)[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMatchSpanGlob(t *testing.T) {
+	start, end, ok := MatchSpan("prod-eu", "prod-*", false, false)
+	if !ok {
+		t.Fatal("expected a glob match")
+	}
+	if got := "prod-eu"[start:end]; got != "prod-eu" {
+		t.Errorf("glob span = %q, want %q", got, "prod-eu")
+	}
+
+	if _, _, ok := MatchSpan("prod-eu", "staging-*", false, false); ok {
+		t.Error("expected no glob match for staging-*")
+	}
+}
@@ -0,0 +1,100 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Select evaluates a set expression like "euprod + usprod - canary" against
+// contexts. Each bare term is a pattern, matched against context names the
+// same way Apply's include patterns are (substring, or subsequence when
+// fuzzy is set); "+" unions its matches into the running set and "-"
+// subtracts them. There's no operator precedence to speak of since both
+// operators carry equal weight: the expression is evaluated strictly left
+// to right, so "a - b + c" drops b's matches from a, then adds c's back in.
+// A leading term with no operator is implicitly unioned in. A term that
+// matches no context is not an error; it simply contributes nothing.
+//
+// The result preserves contexts' original relative order from the input
+// slice, the same as Apply. With ignoreCase set, terms match regardless of
+// casing, the same as Apply's include/exclude patterns.
+func Select(contexts []string, expr string, fuzzy, ignoreCase bool) ([]string, error) {
+	terms, err := tokenizeSelect(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(contexts))
+	for _, term := range terms {
+		matched := matchingContexts(contexts, term.pattern, fuzzy, ignoreCase)
+		switch term.op {
+		case '+':
+			for _, c := range matched {
+				selected[c] = true
+			}
+		case '-':
+			for _, c := range matched {
+				delete(selected, c)
+			}
+		}
+	}
+
+	var result []string
+	for _, c := range contexts {
+		if selected[c] {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// selectTerm is one "<op> <pattern>" pair in a --select expression, with a
+// leading term's implicit operator normalized to '+'.
+type selectTerm struct {
+	op      byte // '+' or '-'
+	pattern string
+}
+
+// tokenizeSelect splits expr on whitespace into a sequence of selectTerms,
+// rejecting anything that isn't "pattern (+|- pattern)*".
+func tokenizeSelect(expr string) ([]selectTerm, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("--select: empty expression")
+	}
+
+	var terms []selectTerm
+	op := byte('+')
+	expectPattern := true
+	for _, f := range fields {
+		if f == "+" || f == "-" {
+			if expectPattern {
+				return nil, fmt.Errorf("--select: unexpected operator %q", f)
+			}
+			op = f[0]
+			expectPattern = true
+			continue
+		}
+		if !expectPattern {
+			return nil, fmt.Errorf("--select: expected an operator before %q", f)
+		}
+		terms = append(terms, selectTerm{op: op, pattern: f})
+		op = '+'
+		expectPattern = false
+	}
+	if expectPattern {
+		return nil, fmt.Errorf("--select: expression ends with an operator")
+	}
+	return terms, nil
+}
+
+// matchingContexts returns the subset of contexts that pattern matches.
+func matchingContexts(contexts []string, pattern string, fuzzy, ignoreCase bool) []string {
+	var matched []string
+	for _, c := range contexts {
+		if matchPattern(c, pattern, fuzzy, ignoreCase) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
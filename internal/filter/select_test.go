@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectUnionAndSubtract(t *testing.T) {
+	contexts := []string{"euprod-1", "usprod-1", "canary-eu", "staging"}
+
+	got, err := Select(contexts, "prod + canary - canary-eu", false, false)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	want := []string{"euprod-1", "usprod-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Select = %v, want %v", got, want)
+	}
+}
+
+// TestSelectLeftToRightPrecedence verifies there's no "+ binds tighter than
+// -" precedence: operators apply strictly in the order they appear.
+func TestSelectLeftToRightPrecedence(t *testing.T) {
+	contexts := []string{"prod-eu", "prod-us", "canary"}
+
+	// Evaluated left to right: start with everything matching "prod" (both),
+	// subtract "prod-eu" (leaves prod-us), then add back "canary".
+	got, err := Select(contexts, "prod - prod-eu + canary", false, false)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	want := []string{"prod-us", "canary"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Select = %v, want %v", got, want)
+	}
+}
+
+// TestSelectUnknownReference verifies a term matching no context is not an
+// error; it just contributes nothing to the set.
+func TestSelectUnknownReference(t *testing.T) {
+	contexts := []string{"prod-eu", "prod-us"}
+
+	got, err := Select(contexts, "prod + does-not-exist", false, false)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	want := []string{"prod-eu", "prod-us"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Select = %v, want %v", got, want)
+	}
+}
+
+func TestSelectInvalidExpression(t *testing.T) {
+	contexts := []string{"prod-eu"}
+
+	if _, err := Select(contexts, "", false, false); err == nil {
+		t.Error("expected error for empty expression")
+	}
+	if _, err := Select(contexts, "prod +", false, false); err == nil {
+		t.Error("expected error for trailing operator")
+	}
+	if _, err := Select(contexts, "+ prod", false, false); err == nil {
+		t.Error("expected error for leading operator")
+	}
+	if _, err := Select(contexts, "prod prod-eu", false, false); err == nil {
+		t.Error("expected error for two patterns with no operator between them")
+	}
+}
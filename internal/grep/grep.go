@@ -0,0 +1,79 @@
+// Package grep filters and matches kubectl output against patterns, the way
+// a user would otherwise pipe output through grep(1).
+package grep
+
+import (
+	"regexp"
+	"strings"
+)
+
+// lineMatcher compiles pattern into a function reporting whether a line
+// matches it, treating "|" as native regex alternation rather than
+// splitting on it. A pattern wrapped in slashes (/pattern/) is always
+// treated as an explicit regex, with any compile error returned to the
+// caller. Otherwise, if the bare pattern fails to compile as a regex (e.g.
+// it contains a stray "[" or "(" the user didn't mean as regex syntax),
+// matching falls back to a plain substring check instead of erroring,
+// since most hand-typed patterns are literals or simple alternations
+// rather than full regexes.
+func lineMatcher(pattern string) (func(line string) bool, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	if re, err := regexp.Compile(pattern); err == nil {
+		return re.MatchString, nil
+	}
+	return func(line string) bool { return strings.Contains(line, pattern) }, nil
+}
+
+// Match reports whether any line of output matches pattern.
+func Match(output, pattern string) (bool, error) {
+	match, err := lineMatcher(pattern)
+	if err != nil {
+		return false, err
+	}
+	return match(output), nil
+}
+
+// Count returns the number of lines in output matching pattern, the way
+// `grep -c` would. If invert is true, it counts lines that do NOT match
+// instead, the way `grep -vc` would.
+func Count(output, pattern string, invert bool) (int, error) {
+	match, err := lineMatcher(pattern)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		if match(line) != invert {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FilterLines returns only the lines of output matching pattern, joined
+// back with newlines, the way piping through `grep` would. If invert is
+// true, it keeps only lines that do NOT match instead, the way `grep -v`
+// would; an empty pattern matches every line, so inverting it filters
+// everything out. If keepHeader is true, the first line is always kept
+// regardless of pattern, so a kubectl column header survives a pattern
+// that only matches data rows.
+func FilterLines(output, pattern string, invert, keepHeader bool) (string, error) {
+	match, err := lineMatcher(pattern)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(output, "\n")
+	kept := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if (keepHeader && i == 0) || match(line) != invert {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n"), nil
+}
@@ -0,0 +1,122 @@
+package grep
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	output := "pod-a Running\npod-b Pending\npod-c Running\n"
+	got, err := Count(output, "Running", false)
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 2 {
+		t.Errorf("Count() = %d, want 2", got)
+	}
+}
+
+func TestCountInvert(t *testing.T) {
+	output := "pod-a Running\npod-b Pending\npod-c Running\n"
+	got, err := Count(output, "Running", true)
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 2 {
+		t.Errorf("Count() with invert = %d, want 2", got)
+	}
+}
+
+func TestFilterLines(t *testing.T) {
+	output := "pod-a Running\npod-b Pending\npod-c Running"
+	got, err := FilterLines(output, "Running", false, false)
+	if err != nil {
+		t.Fatalf("FilterLines: %v", err)
+	}
+	want := "pod-a Running\npod-c Running"
+	if got != want {
+		t.Errorf("FilterLines() = %q, want %q", got, want)
+	}
+}
+
+func TestFilterLinesKeepHeader(t *testing.T) {
+	output := "NAME READY STATUS\npod-a 1/1 Running\npod-b 0/1 Pending"
+	got, err := FilterLines(output, "Running", false, true)
+	if err != nil {
+		t.Fatalf("FilterLines: %v", err)
+	}
+	want := "NAME READY STATUS\npod-a 1/1 Running"
+	if got != want {
+		t.Errorf("FilterLines() with keepHeader = %q, want %q", got, want)
+	}
+}
+
+func TestFilterLinesInvert(t *testing.T) {
+	output := "pod-a Running\npod-b Pending\npod-c Running"
+	got, err := FilterLines(output, "Running", true, false)
+	if err != nil {
+		t.Fatalf("FilterLines: %v", err)
+	}
+	want := "pod-b Pending"
+	if got != want {
+		t.Errorf("FilterLines() with invert = %q, want %q", got, want)
+	}
+}
+
+func TestFilterLinesEmptyPatternInvertExcludesEverything(t *testing.T) {
+	output := "pod-a Running\npod-b Pending"
+	got, err := FilterLines(output, "", true, false)
+	if err != nil {
+		t.Fatalf("FilterLines: %v", err)
+	}
+	if got != "" {
+		t.Errorf("FilterLines() with empty pattern inverted = %q, want \"\"", got)
+	}
+}
+
+func TestCountAlternation(t *testing.T) {
+	output := "pod-a CrashLoopBackOff\npod-b Running\npod-c ImagePullBackOff\n"
+	got, err := Count(output, "CrashLoopBackOff|ImagePullBackOff", false)
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 2 {
+		t.Errorf("Count() with alternation = %d, want 2", got)
+	}
+}
+
+func TestCountAlternationWithRegexInEachBranch(t *testing.T) {
+	output := "pod-a foo123bar\npod-b baz\npod-c nomatch\n"
+	got, err := Count(output, `foo.*bar|baz`, false)
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 2 {
+		t.Errorf("Count() with regex alternation = %d, want 2", got)
+	}
+}
+
+func TestCountInvalidRegexFallsBackToSubstring(t *testing.T) {
+	output := "pod-a [pending]\npod-b running\n"
+	got, err := Count(output, "[pending", false)
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Count() with invalid regex = %d, want 1 (substring fallback)", got)
+	}
+}
+
+func TestMatchExplicitSlashRegexForm(t *testing.T) {
+	got, err := Match("pod-a foo123bar", "/foo.*bar/")
+	if err != nil {
+		t.Fatalf("Match: %v", err)
+	}
+	if !got {
+		t.Errorf("Match() with /pattern/ form = false, want true")
+	}
+}
+
+func TestMatchExplicitSlashRegexFormCompileError(t *testing.T) {
+	_, err := Match("anything", "/[unterminated/")
+	if err == nil {
+		t.Fatal("expected a compile error for an invalid explicit regex")
+	}
+}
@@ -0,0 +1,236 @@
+// Package kube discovers Kubernetes contexts from the local kubectl configuration.
+package kube
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Client queries kubectl for context information.
+type Client struct {
+	lazy bool
+
+	once     sync.Once
+	contexts []string
+	err      error
+
+	contextsFile string
+	kubeconfig   string
+}
+
+// NewClient returns a Client that shells out to the kubectl binary on PATH.
+func NewClient() *Client {
+	return &Client{}
+}
+
+// NewLazyClient returns a Client that defers running kubectl until Contexts
+// is first called, instead of eagerly discovering contexts up front. This
+// keeps commands that don't need the full context list (or that tolerate
+// discovery failing) off the critical path to startup.
+func NewLazyClient() *Client {
+	return &Client{lazy: true}
+}
+
+// UseContextsFile makes GetContexts (and Contexts) read context names from
+// path, one per line, instead of shelling out to kubectl config
+// get-contexts, for environments (e.g. CI) that have a known list of
+// context names but no populated kubeconfig. It checks path exists up
+// front, so a typo is reported clearly instead of surfacing later as a
+// confusing "no such context" from kubectl itself.
+func (c *Client) UseContextsFile(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("--contexts-file: %w", err)
+	}
+	c.contextsFile = path
+	return nil
+}
+
+// UseKubeconfig makes every kubectl invocation this Client makes pass
+// --kubeconfig path, instead of relying on $KUBECONFIG or kubectl's
+// default (~/.kube/config). It checks path exists up front, consistent
+// with UseContextsFile, so a typo is reported clearly rather than
+// surfacing later as a confusing kubectl error.
+func (c *Client) UseKubeconfig(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("--kubeconfig: %w", err)
+	}
+	c.kubeconfig = path
+	return nil
+}
+
+// Kubeconfig returns the path set via UseKubeconfig, or "" if none was set.
+func (c *Client) Kubeconfig() string {
+	return c.kubeconfig
+}
+
+// globalArgs prepends --kubeconfig ahead of args, if UseKubeconfig has set
+// one, so every kubectl invocation this Client makes targets the same file.
+func (c *Client) globalArgs(args ...string) []string {
+	if c.kubeconfig == "" {
+		return args
+	}
+	return append([]string{"--kubeconfig", c.kubeconfig}, args...)
+}
+
+// kubectlBinary returns the kubectl binary to shell out to: $KXCTL_KUBECTL
+// if set, for systems where it's installed under a different name or path
+// (kubectl.exe, a wrapper script, ...), falling back to "kubectl" on PATH.
+func kubectlBinary() string {
+	if bin := os.Getenv("KXCTL_KUBECTL"); bin != "" {
+		return bin
+	}
+	return "kubectl"
+}
+
+// Contexts returns the discovered context names, running kubectl on first
+// call for a lazy client and caching the result (success or failure) for
+// subsequent calls.
+func (c *Client) Contexts() ([]string, error) {
+	if !c.lazy {
+		return c.GetContexts()
+	}
+	c.once.Do(func() {
+		c.contexts, c.err = c.GetContexts()
+	})
+	return c.contexts, c.err
+}
+
+// ContextInfo describes one context's entry in kubeconfig, beyond just its
+// name.
+type ContextInfo struct {
+	Name string
+
+	// Namespace is the default namespace configured for this context, or
+	// "" if the context doesn't set one (kubectl then falls back to
+	// "default").
+	Namespace string
+
+	// Server is the API server URL of the cluster this context points at.
+	Server string
+
+	// User is the name of the kubeconfig user entry this context
+	// authenticates as.
+	User string
+}
+
+// GetContextInfos returns the kubeconfig metadata for every context,
+// including each one's configured default namespace, cluster API server
+// URL, and user.
+func (c *Client) GetContextInfos() ([]ContextInfo, error) {
+	cmd := exec.Command(kubectlBinary(), c.globalArgs("config", "view", "-o", "json")...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("reading context info: %w: %s", err, out.String())
+	}
+	return parseContextInfos(out.Bytes())
+}
+
+// kubeconfigView is the subset of `kubectl config view -o json`'s schema
+// parseContextInfos needs.
+type kubeconfigView struct {
+	Contexts []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster   string `json:"cluster"`
+			Namespace string `json:"namespace"`
+			User      string `json:"user"`
+		} `json:"context"`
+	} `json:"contexts"`
+	Clusters []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			Server string `json:"server"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+}
+
+// parseContextInfos parses the JSON output of `kubectl config view -o
+// json` into one ContextInfo per context, resolving each context's
+// cluster name to that cluster's API server URL. It's split out from
+// GetContextInfos so the parsing can be unit tested without shelling out
+// to kubectl.
+func parseContextInfos(data []byte) ([]ContextInfo, error) {
+	var view kubeconfigView
+	if err := json.Unmarshal(data, &view); err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig JSON: %w", err)
+	}
+
+	servers := make(map[string]string, len(view.Clusters))
+	for _, cl := range view.Clusters {
+		servers[cl.Name] = cl.Cluster.Server
+	}
+
+	infos := make([]ContextInfo, 0, len(view.Contexts))
+	for _, c := range view.Contexts {
+		infos = append(infos, ContextInfo{
+			Name:      c.Name,
+			Namespace: c.Context.Namespace,
+			Server:    servers[c.Context.Cluster],
+			User:      c.Context.User,
+		})
+	}
+	return infos, nil
+}
+
+// GetCurrentContext returns the name of the context kubectl currently
+// treats as active. It returns a clear error if no current context is set.
+func (c *Client) GetCurrentContext() (string, error) {
+	cmd := exec.Command(kubectlBinary(), c.globalArgs("config", "current-context")...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("no current context is set: %w: %s", err, strings.TrimSpace(out.String()))
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// GetContexts returns the names of every context defined in the active
+// kubeconfig, in the order kubectl reports them, with duplicates dropped
+// (preserving first-seen order) since a merged $KUBECONFIG can otherwise
+// report the same context name more than once. This always shells out to
+// kubectl itself, regardless of the --bin a caller passes to
+// executor.ExecuteCommand for the actual run: contexts live in the
+// kubeconfig, not in whatever binary (kubectl, helm, ...) ends up driven
+// against them, so kubectl remains the one source of truth for discovery.
+func (c *Client) GetContexts() ([]string, error) {
+	if c.contextsFile != "" {
+		contexts, err := LoadContextsFromFile(c.contextsFile)
+		if err != nil {
+			return nil, err
+		}
+		return MergeContexts(contexts), nil
+	}
+
+	cmd := exec.Command(kubectlBinary(), c.globalArgs("config", "get-contexts", "-o", "name")...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("listing contexts: %w: %s", err, out.String())
+	}
+
+	return MergeContexts(parseContextNames(out.String())), nil
+}
+
+// parseContextNames splits the newline-delimited output of `kubectl config
+// get-contexts -o name` into context names, skipping blank lines. It's
+// split out from GetContexts so the parsing can be unit tested without
+// shelling out to kubectl.
+func parseContextNames(output string) []string {
+	var contexts []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			contexts = append(contexts, line)
+		}
+	}
+	return contexts
+}
@@ -0,0 +1,180 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGetContextsFromFile verifies GetContexts reads from the file given to
+// UseContextsFile instead of shelling out to kubectl.
+func TestGetContextsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contexts.txt")
+	if err := os.WriteFile(path, []byte("prod-eu\n# comment\n\nprod-us\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := NewClient()
+	if err := c.UseContextsFile(path); err != nil {
+		t.Fatalf("UseContextsFile: %v", err)
+	}
+
+	got, err := c.GetContexts()
+	if err != nil {
+		t.Fatalf("GetContexts: %v", err)
+	}
+	want := []string{"prod-eu", "prod-us"}
+	if len(got) != len(want) {
+		t.Fatalf("GetContexts() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("context %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestUseContextsFileMissing verifies a missing file is rejected up front
+// rather than surfacing later as a confusing error from GetContexts.
+func TestUseContextsFileMissing(t *testing.T) {
+	c := NewClient()
+	if err := c.UseContextsFile(filepath.Join(t.TempDir(), "does-not-exist.txt")); err == nil {
+		t.Fatal("expected an error for a missing contexts file")
+	}
+}
+
+// TestUseKubeconfigMissing verifies a missing kubeconfig path is rejected up
+// front rather than surfacing later as a confusing kubectl error.
+func TestUseKubeconfigMissing(t *testing.T) {
+	c := NewClient()
+	if err := c.UseKubeconfig(filepath.Join(t.TempDir(), "does-not-exist.yaml")); err == nil {
+		t.Fatal("expected an error for a missing kubeconfig file")
+	}
+}
+
+// TestParseContextNamesDedupesAndTrims verifies parseContextNames skips
+// blank lines and trims whitespace, without yet deduplicating (that's
+// GetContexts's job via MergeContexts).
+func TestParseContextNamesDedupesAndTrims(t *testing.T) {
+	got := parseContextNames("prod-eu\n\n  prod-us  \nprod-eu\n")
+	want := []string{"prod-eu", "prod-us", "prod-eu"}
+	if len(got) != len(want) {
+		t.Fatalf("parseContextNames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestParseContextNamesEmptyOutput verifies parseContextNames returns no
+// contexts for empty kubectl output, rather than a phantom single empty
+// string the way a bare strings.Split on "" would.
+func TestParseContextNamesEmptyOutput(t *testing.T) {
+	if got := parseContextNames(""); len(got) != 0 {
+		t.Fatalf("parseContextNames(\"\") = %v, want an empty slice", got)
+	}
+}
+
+// TestGetContextsDedupesMergedKubeconfigEntries verifies GetContexts drops
+// duplicate context names (e.g. from a merged $KUBECONFIG) while preserving
+// first-seen order, exercised through the contexts-file path so it doesn't
+// require a real kubectl binary.
+func TestGetContextsDedupesMergedKubeconfigEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contexts.txt")
+	if err := os.WriteFile(path, []byte("prod-eu\nprod-us\nprod-eu\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	c := NewClient()
+	if err := c.UseContextsFile(path); err != nil {
+		t.Fatalf("UseContextsFile: %v", err)
+	}
+
+	got, err := c.GetContexts()
+	if err != nil {
+		t.Fatalf("GetContexts: %v", err)
+	}
+	want := []string{"prod-eu", "prod-us"}
+	if len(got) != len(want) {
+		t.Fatalf("GetContexts() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("context %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// TestParseContextInfos verifies parseContextInfos joins each context's
+// cluster reference to that cluster's API server URL, alongside its
+// namespace and user.
+func TestParseContextInfos(t *testing.T) {
+	data := []byte(`{
+		"contexts": [
+			{"name": "prod-eu", "context": {"cluster": "eu-cluster", "namespace": "default", "user": "eu-user"}},
+			{"name": "prod-us", "context": {"cluster": "us-cluster", "user": "us-user"}}
+		],
+		"clusters": [
+			{"name": "eu-cluster", "cluster": {"server": "https://eu.example.com"}},
+			{"name": "us-cluster", "cluster": {"server": "https://us.example.com"}}
+		]
+	}`)
+
+	got, err := parseContextInfos(data)
+	if err != nil {
+		t.Fatalf("parseContextInfos: %v", err)
+	}
+	want := []ContextInfo{
+		{Name: "prod-eu", Namespace: "default", Server: "https://eu.example.com", User: "eu-user"},
+		{Name: "prod-us", Server: "https://us.example.com", User: "us-user"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseContextInfos() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("info %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestParseContextInfosInvalidJSON verifies malformed kubeconfig JSON
+// surfaces a clear error instead of a zero-value result.
+func TestParseContextInfosInvalidJSON(t *testing.T) {
+	if _, err := parseContextInfos([]byte("not json")); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
+
+// TestGlobalArgsKubeconfig verifies globalArgs prepends --kubeconfig ahead
+// of the given args once UseKubeconfig has set one, and leaves args
+// untouched otherwise.
+func TestGlobalArgsKubeconfig(t *testing.T) {
+	c := NewClient()
+	got := c.globalArgs("config", "get-contexts")
+	want := []string{"config", "get-contexts"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("globalArgs() with no kubeconfig = %v, want %v", got, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "kubeconfig.yaml")
+	if err := os.WriteFile(path, []byte(""), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := c.UseKubeconfig(path); err != nil {
+		t.Fatalf("UseKubeconfig: %v", err)
+	}
+
+	got = c.globalArgs("config", "get-contexts")
+	want = []string{"--kubeconfig", path, "config", "get-contexts"}
+	if len(got) != len(want) {
+		t.Fatalf("globalArgs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
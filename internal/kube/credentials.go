@@ -0,0 +1,37 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// kubeconfigPath returns the kubeconfig file kxctl (and kubectl) would use:
+// $KUBECONFIG if set, otherwise ~/.kube/config.
+func kubeconfigPath() (string, error) {
+	if p := os.Getenv("KUBECONFIG"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// CredentialAge returns how long it's been since the active kubeconfig file
+// was last modified, as a best-effort proxy for credential/token
+// freshness. It's heuristic: kxctl has no reliable way to inspect
+// per-context exec-plugin token caches, so a recently refreshed kubeconfig
+// is the closest available signal.
+func CredentialAge() (time.Duration, error) {
+	p, err := kubeconfigPath()
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(info.ModTime()), nil
+}
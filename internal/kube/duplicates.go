@@ -0,0 +1,76 @@
+package kube
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DuplicateContext describes a context name that appears in more than one
+// of the files merged via $KUBECONFIG. WinningFile is the one kubectl
+// actually uses for that name (the first file listing it, per kubectl's
+// merge order); OtherFiles are the ones that were shadowed.
+type DuplicateContext struct {
+	Name        string
+	WinningFile string
+	OtherFiles  []string
+}
+
+// DetectDuplicateContexts checks every file in $KUBECONFIG for context
+// names that collide, since kubectl silently uses whichever file it finds
+// first and gives no warning of its own. It returns nil if $KUBECONFIG
+// isn't set to multiple files, since there's nothing to merge.
+func DetectDuplicateContexts() ([]DuplicateContext, error) {
+	files := filepath.SplitList(os.Getenv("KUBECONFIG"))
+	if len(files) < 2 {
+		return nil, nil
+	}
+
+	firstFile := make(map[string]string, len(files))
+	dupIndex := make(map[string]int)
+	var dups []DuplicateContext
+
+	for _, f := range files {
+		names, err := contextNamesInFile(f)
+		if err != nil {
+			// An unreadable file in the merge list isn't this check's
+			// problem to report; kubectl itself will surface that.
+			continue
+		}
+		for _, name := range names {
+			winner, seen := firstFile[name]
+			if !seen {
+				firstFile[name] = f
+				continue
+			}
+			if idx, ok := dupIndex[name]; ok {
+				dups[idx].OtherFiles = append(dups[idx].OtherFiles, f)
+				continue
+			}
+			dupIndex[name] = len(dups)
+			dups = append(dups, DuplicateContext{Name: name, WinningFile: winner, OtherFiles: []string{f}})
+		}
+	}
+	return dups, nil
+}
+
+func contextNamesInFile(path string) ([]string, error) {
+	cmd := exec.Command(kubectlBinary(), "config", "get-contexts", "--kubeconfig", path, "-o", "name")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
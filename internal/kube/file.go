@@ -0,0 +1,49 @@
+package kube
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadContextsFromFile reads one context name per line from path, ignoring
+// blank lines and "#"-prefixed comments. It's used to supplement the
+// contexts kubectl already knows about with ones managed out-of-band (e.g.
+// a centrally distributed inventory that hasn't been merged into the local
+// kubeconfig yet).
+func LoadContextsFromFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading contexts file: %w", err)
+	}
+	defer f.Close()
+
+	var contexts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		contexts = append(contexts, line)
+	}
+	return contexts, scanner.Err()
+}
+
+// MergeContexts combines context names from multiple sources (e.g. kubectl
+// and an extra-contexts file), preserving first-seen order and dropping
+// duplicates.
+func MergeContexts(sources ...[]string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, source := range sources {
+		for _, name := range source {
+			if !seen[name] {
+				seen[name] = true
+				merged = append(merged, name)
+			}
+		}
+	}
+	return merged
+}
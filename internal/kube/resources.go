@@ -0,0 +1,44 @@
+package kube
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// ExpandResourceNames lists resources of resourceType in a context and
+// returns the short names (without the "type/" prefix kubectl adds to
+// `-o name` output) that match the glob pattern. It is used to resolve a
+// trailing wildcard like "myapp-*" into concrete resource names per
+// context, since kubectl itself does not glob resource names.
+func ExpandResourceNames(ctxName, resourceType, pattern string) ([]string, error) {
+	cmd := exec.Command(kubectlBinary(), "--context", ctxName, "get", resourceType, "-o", "name")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("listing %s in context %s: %w: %s", resourceType, ctxName, err, out.String())
+	}
+
+	var matched []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name := line
+		if idx := strings.IndexByte(line, '/'); idx != -1 {
+			name = line[idx+1:]
+		}
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if ok {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
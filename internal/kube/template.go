@@ -0,0 +1,31 @@
+package kube
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CreateContextsFromTemplate creates one kubeconfig context per name by
+// running `kubectl config set-cluster` and `kubectl config set-context`
+// against it directly. serverPattern may contain "{n}" as a placeholder for
+// the context's name, so a single pattern can stand in for many similar
+// clusters (e.g. "https://{n}.k8s.example.com"). Every created context
+// shares the same kubeconfig user.
+func CreateContextsFromTemplate(names []string, serverPattern, user string) error {
+	for _, n := range names {
+		server := strings.ReplaceAll(serverPattern, "{n}", n)
+		clusterName := "kxctl-" + n
+
+		setCluster := exec.Command(kubectlBinary(), "config", "set-cluster", clusterName, "--server="+server)
+		if out, err := setCluster.CombinedOutput(); err != nil {
+			return fmt.Errorf("set-cluster %s: %w: %s", clusterName, err, strings.TrimSpace(string(out)))
+		}
+
+		setContext := exec.Command(kubectlBinary(), "config", "set-context", n, "--cluster="+clusterName, "--user="+user)
+		if out, err := setContext.CombinedOutput(); err != nil {
+			return fmt.Errorf("set-context %s: %w: %s", n, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package laststate persists the set of contexts that failed on the most
+// recent `kxctl exec` run, so a later invocation can retarget just those
+// contexts with --retry-failed instead of re-running against the whole
+// fleet.
+package laststate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// path returns the on-disk location of the last-failed record.
+func path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kxctl", "last-failed.json"), nil
+}
+
+// SaveFailed records contexts as the failed set from the run that just
+// finished, overwriting whatever was recorded before. An empty contexts
+// still overwrites the file, so a fully successful run correctly clears
+// --retry-failed's target set for next time.
+func SaveFailed(contexts []string) error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(contexts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+// LoadFailed returns the contexts that failed on the last run, or an empty
+// slice if none is recorded yet.
+func LoadFailed() ([]string, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var contexts []string
+	if err := json.Unmarshal(data, &contexts); err != nil {
+		return nil, err
+	}
+	return contexts, nil
+}
@@ -0,0 +1,144 @@
+// Package picker implements a minimal terminal multi-select list (arrow
+// keys, space to toggle, enter to confirm), for --interactive context
+// selection without shelling out to fzf or pulling in a TUI dependency.
+package picker
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Select renders items as a checkbox list on stderr and blocks until the
+// user confirms a selection (enter) or cancels (q or Esc), returning the
+// checked items in their original order. It starts with every item
+// checked, since --interactive is meant to narrow down an otherwise
+// unfiltered run rather than build a selection from nothing.
+func Select(items []string) ([]string, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		return nil, fmt.Errorf("picker: enabling raw terminal mode: %w", err)
+	}
+	defer restore()
+
+	checked := make([]bool, len(items))
+	for i := range checked {
+		checked[i] = true
+	}
+	cursor := 0
+
+	render(items, checked, cursor)
+	buf := make([]byte, 3)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("picker: reading input: %w", err)
+		}
+
+		switch {
+		case n == 1 && buf[0] == '\r' || n == 1 && buf[0] == '\n':
+			return selected(items, checked), nil
+		case n == 1 && (buf[0] == 'q' || buf[0] == 3): // q, or Ctrl-C
+			return nil, fmt.Errorf("picker: selection cancelled")
+		case n == 1 && buf[0] == ' ':
+			checked[cursor] = !checked[cursor]
+		case n == 1 && buf[0] == 27 && isEscapeOnly(buf, n): // bare Esc
+			return nil, fmt.Errorf("picker: selection cancelled")
+		case n == 3 && buf[0] == 27 && buf[1] == '[' && buf[2] == 'A': // up
+			cursor = (cursor - 1 + len(items)) % len(items)
+		case n == 3 && buf[0] == 27 && buf[1] == '[' && buf[2] == 'B': // down
+			cursor = (cursor + 1) % len(items)
+		}
+		render(items, checked, cursor)
+	}
+}
+
+// isEscapeOnly reports whether a single byte 27 was a standalone Esc
+// keypress rather than the start of an arrow-key escape sequence that just
+// happened to be read one byte at a time.
+func isEscapeOnly(buf []byte, n int) bool {
+	return n == 1 && buf[0] == 27
+}
+
+// selected returns the items whose checked flag is set, preserving order.
+func selected(items []string, checked []bool) []string {
+	var result []string
+	for i, c := range checked {
+		if c {
+			result = append(result, items[i])
+		}
+	}
+	return result
+}
+
+// render redraws the checkbox list in place, moving the cursor back to the
+// top of the list first so repeated calls overwrite rather than scroll.
+func render(items []string, checked []bool, cursor int) {
+	fmt.Fprintf(os.Stderr, "\x1b[%dA", len(items))
+	for i, item := range items {
+		box := "[ ]"
+		if checked[i] {
+			box = "[x]"
+		}
+		pointer := "  "
+		if i == cursor {
+			pointer = "> "
+		}
+		fmt.Fprintf(os.Stderr, "\x1b[2K%s%s %s\r\n", pointer, box, item)
+	}
+}
+
+// termios mirrors the kernel's struct termios (not glibc's extended one,
+// which TCGETS/TCSETS don't use), as read and written by the TCGETS/TCSETS
+// ioctls.
+type termios struct {
+	Iflag uint32
+	Oflag uint32
+	Cflag uint32
+	Lflag uint32
+	Line  byte
+	Cc    [19]byte
+}
+
+const (
+	tcgets = 0x5401
+	tcsets = 0x5402
+
+	lflagISIG   = 0x1
+	lflagICANON = 0x2
+	lflagECHO   = 0x8
+	iflagIXON   = 0x400
+	iflagICRNL  = 0x100
+)
+
+// makeRaw disables canonical mode, echo, and signal generation on fd so
+// keys (including arrow-key escape sequences) can be read one byte at a
+// time instead of waiting for a line, and returns a func that restores the
+// terminal's original settings.
+func makeRaw(fd uintptr) (restore func(), err error) {
+	var original termios
+	if err := ioctl(fd, tcgets, &original); err != nil {
+		return nil, err
+	}
+
+	raw := original
+	raw.Lflag &^= lflagISIG | lflagICANON | lflagECHO
+	raw.Iflag &^= iflagIXON | iflagICRNL
+	if err := ioctl(fd, tcsets, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { ioctl(fd, tcsets, &original) }, nil
+}
+
+func ioctl(fd uintptr, req uintptr, t *termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
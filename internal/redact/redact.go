@@ -0,0 +1,75 @@
+// Package redact applies best-effort regex-based scrubbing to kubectl
+// output before kxctl prints it, for pasting fleet output somewhere public.
+// It's pattern matching, not a guarantee: anything that doesn't look like
+// what a rule is looking for will pass through unredacted.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// rule is one pattern-to-replacement scrub.
+type rule struct {
+	pattern *regexp.Regexp
+	replace string
+}
+
+// builtins are always-on scrubs covering common leak vectors in kubectl
+// output: IPv4 addresses, email addresses, and tokens that look like
+// secrets (cloud access keys, JWTs, and common API key prefixes).
+var builtins = []rule{
+	{regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`), "<redacted-ip>"},
+	{regexp.MustCompile(`\b[\w.+-]+@[\w-]+\.[\w.-]+\b`), "<redacted-email>"},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "<redacted-token>"},
+	{regexp.MustCompile(`\b(?:sk|pk|ghp|ghs|xox[baprs])-[A-Za-z0-9_-]{10,}\b`), "<redacted-token>"},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\b`), "<redacted-jwt>"},
+}
+
+// Redactor holds the set of scrub rules to apply: the built-ins, plus any
+// extra patterns the caller configured, plus (optionally) a per-context
+// name substitution.
+type Redactor struct {
+	rules []rule
+}
+
+// New builds a Redactor from the built-in patterns plus extraPatterns,
+// each compiled as a regexp and replaced with a fixed "<redacted>" marker
+// since an arbitrary caller-supplied pattern doesn't tell us what kind of
+// value it matches.
+func New(extraPatterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]rule{}, builtins...)}
+	for _, p := range extraPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("--redact-pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, rule{re, "<redacted>"})
+	}
+	return r, nil
+}
+
+// WithContextNames adds a literal-replacement rule for every name in
+// contexts, substituting trim(name) wherever the raw name appears in
+// output text. This extends the same scrubbing --display-trim already
+// applies to headers and summaries to the body of the output itself, so a
+// context name baked into kubectl's own output (e.g. in an annotation)
+// gets the same treatment.
+func (r *Redactor) WithContextNames(contexts []string, trim func(string) string) {
+	for _, c := range contexts {
+		trimmed := trim(c)
+		if trimmed == c {
+			continue
+		}
+		r.rules = append(r.rules, rule{regexp.MustCompile(regexp.QuoteMeta(c)), trimmed})
+	}
+}
+
+// Apply runs every configured rule over s in order and returns the
+// scrubbed string.
+func (r *Redactor) Apply(s string) string {
+	for _, rule := range r.rules {
+		s = rule.pattern.ReplaceAllString(s, rule.replace)
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+// Package snapshot persists per-context command output between kxctl runs
+// so that `exec --diff-last` can report only what changed.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store is a flat map of "<commandKey>:<context>" to a hash of the output
+// last observed for that pairing.
+type Store map[string]string
+
+// path returns the on-disk location of the snapshot store.
+func path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kxctl", "snapshots.json"), nil
+}
+
+// Load reads the snapshot store, returning an empty Store if none exists yet.
+func Load() (Store, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return Store{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Store
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the snapshot store to disk, creating its directory if needed.
+func Save(s Store) error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
+
+// Key derives the store key for a given kubectl invocation and context.
+func Key(kubectlArgs []string, context string) string {
+	return Hash(kubectlArgs) + ":" + context
+}
+
+// Hash returns a short content hash of a kubectl invocation, used to key
+// snapshots so unrelated commands against the same context don't collide.
+func Hash(kubectlArgs []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(kubectlArgs, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// HashOutput returns a content hash of command output, for cheap comparison.
+func HashOutput(output string) string {
+	sum := sha256.Sum256([]byte(output))
+	return hex.EncodeToString(sum[:])
+}
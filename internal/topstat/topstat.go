@@ -0,0 +1,139 @@
+// Package topstat parses `kubectl top` tabular output (for both `top
+// nodes` and `top pods`) and aggregates CPU and memory usage across
+// however many rows or contexts it's fed, so callers can print a grand
+// total alongside kxctl's usual per-context results.
+package topstat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Totals holds summed resource usage: CPU in cores (not millicores, to
+// keep the arithmetic unit-free) and memory in bytes.
+type Totals struct {
+	CPUCores    float64
+	MemoryBytes int64
+}
+
+// Add returns the element-wise sum of t and other.
+func (t Totals) Add(other Totals) Totals {
+	return Totals{CPUCores: t.CPUCores + other.CPUCores, MemoryBytes: t.MemoryBytes + other.MemoryBytes}
+}
+
+// Parse sums the CPU and memory columns of kubectl top's tabular output
+// (either `top nodes` or `top pods`, with or without -A/--containers).
+// The header row is located by its CPU(cores)/MEMORY(bytes) columns so
+// the column order and any leading NAMESPACE/POD columns don't matter;
+// rows that don't have enough columns to match the header are skipped.
+func Parse(output string) (Totals, error) {
+	var totals Totals
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	cpuCol, memCol := -1, -1
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if cpuCol == -1 {
+			for i, f := range fields {
+				switch f {
+				case "CPU(cores)":
+					cpuCol = i
+				case "MEMORY(bytes)":
+					memCol = i
+				}
+			}
+			continue // this was the header row
+		}
+		if cpuCol >= len(fields) || memCol >= len(fields) {
+			continue
+		}
+		cores, err := ParseCPU(fields[cpuCol])
+		if err != nil {
+			return Totals{}, fmt.Errorf("topstat: %w", err)
+		}
+		bytes, err := ParseMemory(fields[memCol])
+		if err != nil {
+			return Totals{}, fmt.Errorf("topstat: %w", err)
+		}
+		totals.CPUCores += cores
+		totals.MemoryBytes += bytes
+	}
+	if cpuCol == -1 {
+		return Totals{}, fmt.Errorf("topstat: no CPU(cores)/MEMORY(bytes) header found in output")
+	}
+	return totals, nil
+}
+
+// ParseCPU converts a kubectl top CPU value, e.g. "250m" (millicores) or
+// "2" (whole cores), to cores.
+func ParseCPU(s string) (float64, error) {
+	if strings.HasSuffix(s, "m") {
+		milli, err := strconv.ParseFloat(strings.TrimSuffix(s, "m"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU value %q: %w", s, err)
+		}
+		return milli / 1000, nil
+	}
+	cores, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU value %q: %w", s, err)
+	}
+	return cores, nil
+}
+
+// memoryUnits maps kubectl's binary memory suffixes to a byte multiplier.
+// Longest suffixes are checked first so "Ki" isn't mistaken for a bare "i".
+var memoryUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"Ei", 1 << 60},
+	{"Pi", 1 << 50},
+	{"Ti", 1 << 40},
+	{"Gi", 1 << 30},
+	{"Mi", 1 << 20},
+	{"Ki", 1 << 10},
+}
+
+// ParseMemory converts a kubectl top memory value, e.g. "512Mi" or
+// "2Gi", to bytes. A value with no recognized unit suffix is parsed as a
+// plain byte count.
+func ParseMemory(s string) (int64, error) {
+	for _, u := range memoryUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSuffix(s, u.suffix), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid memory value %q: %w", s, err)
+			}
+			return n * u.multiplier, nil
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory value %q: %w", s, err)
+	}
+	return n, nil
+}
+
+// FormatCPU renders cores the way kubectl top would: millicores below one
+// whole core, otherwise a plain core count.
+func FormatCPU(cores float64) string {
+	if cores < 1 {
+		return fmt.Sprintf("%dm", int64(cores*1000))
+	}
+	return strconv.FormatFloat(cores, 'f', -1, 64)
+}
+
+// FormatMemory renders a byte count using the largest binary unit that
+// keeps the value at least 1, matching kubectl top's own Ki/Mi/Gi style.
+func FormatMemory(bytes int64) string {
+	for _, u := range memoryUnits {
+		if bytes >= u.multiplier {
+			return fmt.Sprintf("%d%s", bytes/u.multiplier, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d", bytes)
+}
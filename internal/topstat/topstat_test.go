@@ -0,0 +1,55 @@
+package topstat
+
+import "testing"
+
+func TestParseNodes(t *testing.T) {
+	output := "NAME     CPU(cores)   CPU%   MEMORY(bytes)   MEMORY%\n" +
+		"node-a   250m         5%     512Mi           10%\n" +
+		"node-b   1            20%    2Gi             40%\n"
+	got, err := Parse(output)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := Totals{CPUCores: 1.25, MemoryBytes: 512<<20 + 2<<30}
+	if got != want {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParsePodsAllNamespaces(t *testing.T) {
+	output := "NAMESPACE   POD      CPU(cores)   MEMORY(bytes)\n" +
+		"default     pod-a    10m          64Mi\n" +
+		"kube-system pod-b    5m           32Mi\n"
+	got, err := Parse(output)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := Totals{CPUCores: 0.015, MemoryBytes: 64<<20 + 32<<20}
+	if got != want {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseNoHeader(t *testing.T) {
+	if _, err := Parse("metrics-server not installed\n"); err == nil {
+		t.Error("expected error for output with no CPU/MEMORY header")
+	}
+}
+
+func TestFormatCPU(t *testing.T) {
+	if got := FormatCPU(0.25); got != "250m" {
+		t.Errorf("FormatCPU(0.25) = %q, want %q", got, "250m")
+	}
+	if got := FormatCPU(2); got != "2" {
+		t.Errorf("FormatCPU(2) = %q, want %q", got, "2")
+	}
+}
+
+func TestFormatMemory(t *testing.T) {
+	if got := FormatMemory(512 << 20); got != "512Mi" {
+		t.Errorf("FormatMemory(512Mi) = %q, want %q", got, "512Mi")
+	}
+	if got := FormatMemory(2 << 30); got != "2Gi" {
+		t.Errorf("FormatMemory(2Gi) = %q, want %q", got, "2Gi")
+	}
+}
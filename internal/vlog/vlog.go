@@ -0,0 +1,44 @@
+// Package vlog provides the leveled debug logging behind kxctl exec's
+// repeatable --verbose flag: command construction, concurrency gate
+// activity, and per-context timing, written to stderr via the standard
+// log package. It stays silent by default, matching kxctl's normal
+// quiet-unless-asked output.
+package vlog
+
+import (
+	"log"
+	"os"
+)
+
+// Logger writes debug messages at or below its level to stderr. A nil
+// *Logger is valid and logs nothing, so callers can pass one through
+// unconditionally instead of checking for --verbose everywhere.
+type Logger struct {
+	level  int
+	target *log.Logger
+}
+
+// New returns a Logger enabled for messages at level and below; level 0
+// (kxctl's default) logs nothing. New(0) is equivalent to a nil *Logger
+// and is never needed in practice: callers leave verbose nil instead.
+func New(level int) *Logger {
+	if level <= 0 {
+		return nil
+	}
+	return &Logger{level: level, target: log.New(os.Stderr, "verbose: ", log.Ltime)}
+}
+
+// Enabled reports whether level would actually print, so a caller can
+// skip building an expensive message (e.g. joining a long argument list)
+// when it would just be discarded.
+func (l *Logger) Enabled(level int) bool {
+	return l != nil && l.level >= level
+}
+
+// Printf logs a formatted message at level, if enabled.
+func (l *Logger) Printf(level int, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	l.target.Printf(format, args...)
+}
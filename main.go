@@ -0,0 +1,8 @@
+// Command kxctl runs kubectl commands across multiple Kubernetes contexts.
+package main
+
+import "github.com/mpizala/kxctl/cmd"
+
+func main() {
+	cmd.Execute()
+}